@@ -0,0 +1,173 @@
+package paths
+
+import "os"
+
+// MatcherFunc is an adapter to use a function as a Matcher.
+type MatcherFunc func(path string) bool
+
+// Match returns f(path).
+func (f MatcherFunc) Match(path string) bool {
+	return f(path)
+}
+
+type allMatcher struct {
+	matchers []Matcher
+}
+
+// MatchAll returns a Matcher which matches paths matched by all of
+// matchers. The matchers are evaluated in order and the evaluation stops at
+// the first matcher which does not match. MatchAll with no matchers
+// matches all paths.
+//
+// The returned Matcher is an EntryMatcher which forwards MatchEntry to the
+// matchers which are EntryMatchers, a Pruner which prunes a directory if
+// one of the matchers prunes it, and an Explainer which explains with the
+// first matcher which does not match.
+func MatchAll(matchers ...Matcher) Matcher {
+	return &allMatcher{matchers: matchers}
+}
+
+func (m *allMatcher) Match(path string) bool {
+	for _, matcher := range m.matchers {
+		if !matcher.Match(path) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *allMatcher) MatchEntry(path string, info os.FileInfo) bool {
+	for _, matcher := range m.matchers {
+		if !matchEntry(matcher, path, info) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *allMatcher) Prune(dir string) bool {
+	for _, matcher := range m.matchers {
+		if p, ok := matcher.(Pruner); ok && p.Prune(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// Explain returns the explanation of the first matcher which does not match
+// the path. If all the matchers match, Include is the first Include of
+// their explanations.
+func (m *allMatcher) Explain(path string) *Explanation {
+	e := &Explanation{Path: path, Matched: true}
+	for _, matcher := range m.matchers {
+		me := explain(matcher, path)
+		if !me.Matched {
+			return me
+		}
+		if e.Include == nil {
+			e.Include = me.Include
+		}
+	}
+	return e
+}
+
+type anyMatcher struct {
+	matchers []Matcher
+}
+
+// MatchAny returns a Matcher which matches paths matched by any of
+// matchers. The matchers are evaluated in order and the evaluation stops at
+// the first matcher which matches. MatchAny with no matchers matches no
+// paths.
+//
+// The returned Matcher is an EntryMatcher which forwards MatchEntry to the
+// matchers which are EntryMatchers, a Pruner which prunes a directory if
+// all of the matchers prune it, and an Explainer which explains with the
+// first matcher which matches.
+func MatchAny(matchers ...Matcher) Matcher {
+	return &anyMatcher{matchers: matchers}
+}
+
+func (m *anyMatcher) Match(path string) bool {
+	for _, matcher := range m.matchers {
+		if matcher.Match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *anyMatcher) MatchEntry(path string, info os.FileInfo) bool {
+	for _, matcher := range m.matchers {
+		if matchEntry(matcher, path, info) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *anyMatcher) Prune(dir string) bool {
+	for _, matcher := range m.matchers {
+		if p, ok := matcher.(Pruner); !ok || !p.Prune(dir) {
+			return false
+		}
+	}
+	return true
+}
+
+// Explain returns the explanation of the first matcher which matches the
+// path. If no matcher matches, Exclude is the first Exclude of their
+// explanations.
+func (m *anyMatcher) Explain(path string) *Explanation {
+	e := &Explanation{Path: path}
+	for _, matcher := range m.matchers {
+		me := explain(matcher, path)
+		if me.Matched {
+			return me
+		}
+		if e.Exclude == nil {
+			e.Exclude = me.Exclude
+		}
+	}
+	return e
+}
+
+type notMatcher struct {
+	matcher Matcher
+}
+
+// Not returns a Matcher which matches paths not matched by matcher.
+//
+// The returned Matcher is an EntryMatcher which forwards MatchEntry to
+// matcher if it is an EntryMatcher, and an Explainer which swaps Include
+// and Exclude of the explanation of matcher. It does not prune any
+// directories.
+func Not(matcher Matcher) Matcher {
+	return &notMatcher{matcher: matcher}
+}
+
+func (m *notMatcher) Match(path string) bool {
+	return !m.matcher.Match(path)
+}
+
+func (m *notMatcher) MatchEntry(path string, info os.FileInfo) bool {
+	return !matchEntry(m.matcher, path, info)
+}
+
+func (m *notMatcher) Explain(path string) *Explanation {
+	me := explain(m.matcher, path)
+	return &Explanation{
+		Path:    path,
+		Matched: !me.Matched,
+		Include: me.Exclude,
+		Exclude: me.Include}
+}
+
+// explain returns the explanation of m.Match(path). If m is not an
+// Explainer, the explanation has neither Include nor Exclude.
+func explain(m Matcher, path string) *Explanation {
+	if e, ok := m.(Explainer); ok {
+		return e.Explain(path)
+	}
+	return &Explanation{Path: path, Matched: m.Match(path)}
+}
@@ -0,0 +1,119 @@
+package paths_test
+
+import (
+	. "github.com/hnakamur/paths"
+	"strings"
+	"testing"
+)
+
+func mustNewMatcher(t *testing.T, includes, excludes []string) Matcher {
+	matcher, err := NewMatcher(includes, excludes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matcher
+}
+
+func testCombined(t *testing.T, matcher Matcher, cases []testCase) {
+	for _, c := range cases {
+		actual := matcher.Match(c.path)
+		if actual != c.matches {
+			t.Errorf("path:%s\texpected:%v\tactual:%v", c.path, c.matches, actual)
+		}
+	}
+}
+
+func TestMatchAll(t *testing.T) {
+	goFiles := mustNewMatcher(t, []string{"**/*.go"}, nil)
+	noVendor := mustNewMatcher(t, nil, []string{"vendor/"})
+	var calls []string
+	short := MatcherFunc(func(path string) bool {
+		calls = append(calls, path)
+		return !strings.HasSuffix(path, "_test.go")
+	})
+
+	matcher := MatchAll(goFiles, noVendor, short)
+	testCombined(t, matcher, []testCase{
+		{"main.go", true},
+		{"main_test.go", false},
+		{"vendor/a/b.go", false},
+		{"README.md", false},
+	})
+	if strings.Join(calls, " ") != "main.go main_test.go" {
+		t.Errorf("calls=%v, expected=[main.go main_test.go]", calls)
+	}
+
+	testCombined(t, MatchAll(), []testCase{{"a", true}})
+
+	pruner := matcher.(Pruner)
+	if !pruner.Prune("vendor") {
+		t.Errorf("Prune(vendor)=false, expected=true")
+	}
+	if pruner.Prune("src") {
+		t.Errorf("Prune(src)=true, expected=false")
+	}
+
+	explainer := matcher.(Explainer)
+	checkExplanation(t, explainer.Explain("main.go"), true,
+		&PatternMatch{Index: 0, Pattern: "**/*.go"}, nil)
+	checkExplanation(t, explainer.Explain("vendor/a/b.go"), false,
+		nil, &PatternMatch{Index: 0, Pattern: "vendor/"})
+	checkExplanation(t, explainer.Explain("main_test.go"), false, nil, nil)
+}
+
+func TestMatchAny(t *testing.T) {
+	docs := mustNewMatcher(t, []string{"docs/**"}, nil)
+	markdown := mustNewMatcher(t, []string{"**/*.md"}, []string{"tmp/"})
+	calls := 0
+	never := MatcherFunc(func(path string) bool {
+		calls++
+		return false
+	})
+
+	matcher := MatchAny(docs, markdown, never)
+	testCombined(t, matcher, []testCase{
+		{"docs/a.txt", true},
+		{"README.md", true},
+		{"tmp/a.md", false},
+		{"main.go", false},
+	})
+	if calls != 2 {
+		t.Errorf("calls=%d, expected=2", calls)
+	}
+
+	testCombined(t, MatchAny(), []testCase{{"a", false}})
+
+	tmp := mustNewMatcher(t, nil, []string{"tmp/"})
+	build := mustNewMatcher(t, nil, []string{"tmp/", "build/"})
+	pruner := MatchAny(tmp, build).(Pruner)
+	if !pruner.Prune("tmp") {
+		t.Errorf("Prune(tmp)=false, expected=true")
+	}
+	if pruner.Prune("build") {
+		t.Errorf("Prune(build)=true, expected=false")
+	}
+	if matcher.(Pruner).Prune("tmp") {
+		t.Errorf("Prune(tmp)=true, expected=false")
+	}
+
+	explainer := matcher.(Explainer)
+	checkExplanation(t, explainer.Explain("README.md"), true,
+		&PatternMatch{Index: 0, Pattern: "**/*.md"}, nil)
+	checkExplanation(t, explainer.Explain("tmp/a.md"), false,
+		nil, &PatternMatch{Index: 0, Pattern: "tmp/"})
+}
+
+func TestNot(t *testing.T) {
+	matcher := Not(mustNewMatcher(t, []string{"**/*.go"}, []string{"vendor/"}))
+	testCombined(t, matcher, []testCase{
+		{"main.go", false},
+		{"vendor/a.go", true},
+		{"README.md", true},
+	})
+	if _, ok := matcher.(Pruner); ok {
+		t.Errorf("Not is a Pruner")
+	}
+	checkExplanation(t, matcher.(Explainer).Explain("vendor/a.go"), true,
+		&PatternMatch{Index: 0, Pattern: "vendor/"},
+		&PatternMatch{Index: 0, Pattern: "**/*.go"})
+}
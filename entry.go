@@ -0,0 +1,61 @@
+package paths
+
+import (
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// An Entry is a file or a directory read recursively. It implements
+// os.FileInfo, and Name returns the base name as os.FileInfo does.
+// Path returns the path starting with the directory to read.
+type Entry struct {
+	name    string      // base name of the file
+	path    string      // the path starting with the directory to read
+	rel     string      // the path relative to the directory to read
+	size    int64       // length in bytes for regular files; system-dependent for others
+	mode    os.FileMode // file mode bits
+	modTime time.Time   // modification time
+	sys     interface{} // underlying data source (can return nil)
+	stat    *Stat       // details from sys, or nil
+	target  string      // resolved path of the followed symbolic link
+}
+
+func newEntry(name string, info os.FileInfo) *Entry {
+	sys := info.Sys()
+	return &Entry{
+		name:    path.Base(name),
+		path:    name,
+		size:    info.Size(),
+		mode:    info.Mode(),
+		modTime: info.ModTime(),
+		sys:     sys,
+		stat:    newStat(sys)}
+}
+
+func (e *Entry) Name() string       { return e.name }
+func (e *Entry) Size() int64        { return e.size }
+func (e *Entry) Mode() os.FileMode  { return e.mode }
+func (e *Entry) ModTime() time.Time { return e.modTime }
+func (e *Entry) IsDir() bool        { return e.Mode().IsDir() }
+func (e *Entry) Sys() interface{}   { return e.sys }
+
+// Path returns the path starting with the directory to read, like
+// "src/lib/a.go" for "a.go" in "src/lib" when the directory is "src".
+func (e *Entry) Path() string { return e.path }
+
+// RelPath returns the path relative to the directory to read, like
+// "lib/a.go".
+func (e *Entry) RelPath() string { return e.rel }
+
+// Depth returns the number of path elements of RelPath, so entries right
+// under the directory to read have the depth 1.
+func (e *Entry) Depth() int { return strings.Count(e.rel, "/") + 1 }
+
+// Parent returns the path of the parent directory, like "src/lib".
+func (e *Entry) Parent() string { return path.Dir(e.path) }
+
+// LinkTarget returns the resolved path of the target if the entry is a
+// followed symbolic link. Otherwise it returns an empty string.
+func (e *Entry) LinkTarget() string { return e.target }
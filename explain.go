@@ -0,0 +1,41 @@
+package paths
+
+// An Explainer is a Matcher which can explain why it matches or does not
+// match a path, like 'git check-ignore -v'.
+type Explainer interface {
+	Matcher
+
+	// Explain returns the explanation of Match(path).
+	Explain(path string) *Explanation
+}
+
+// An Explanation tells why a Matcher matches or does not match a path.
+type Explanation struct {
+	// Path is the explained path.
+	Path string
+
+	// Matched is the result of Match(Path).
+	Matched bool
+
+	// Include is the first include pattern, or the negated rule which
+	// matched the path. It is nil if no such pattern matched the path.
+	Include *PatternMatch
+
+	// Exclude is the first exclude pattern, or the rule which excluded the
+	// path. It is nil if no such pattern matched the path.
+	Exclude *PatternMatch
+}
+
+// A PatternMatch is a pattern which matched a path.
+type PatternMatch struct {
+	// Index is the index of the pattern in the given patterns or rules.
+	Index int
+
+	// Pattern is the pattern as given, including the leading '!' of a
+	// negated rule.
+	Pattern string
+
+	// Line is the line number of the pattern in a .gitignore file, or zero
+	// for patterns not read from a file.
+	Line int
+}
@@ -0,0 +1,48 @@
+package paths
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// foldRune returns the smallest rune which is equivalent to r under the
+// Unicode simple case folding, which is the same folding as strings.EqualFold
+// and the (?i) flag of regexp.
+func foldRune(r rune) rune {
+	min := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < min {
+			min = f
+		}
+	}
+	return min
+}
+
+// compareFold compares a and b under the Unicode simple case folding.
+func compareFold(a, b string) int {
+	for a != "" && b != "" {
+		ra, na := utf8.DecodeRuneInString(a)
+		rb, nb := utf8.DecodeRuneInString(b)
+		if fa, fb := foldRune(ra), foldRune(rb); fa != fb {
+			if fa < fb {
+				return -1
+			}
+			return 1
+		}
+		a, b = a[na:], b[nb:]
+	}
+	return len(a) - len(b)
+}
+
+// compareNames compares file names. If fold is true, names are compared
+// under the Unicode simple case folding first, and names equal under the
+// folding are ordered by bytes.
+func compareNames(a, b string, fold bool) int {
+	if fold {
+		if c := compareFold(a, b); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,256 @@
+package paths
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+type gitignoreMatcher struct {
+	rules []*rule
+}
+
+// gitignoreOptions are options to compile patterns converted from
+// .gitignore patterns.
+var gitignoreOptions = &MatcherOptions{LiteralBraces: true}
+
+// ParseGitignore reads patterns in the .gitignore format from r and returns
+// a new Matcher. The returned Matcher matches paths which are not ignored
+// by the patterns. Paths are relative to the directory which contains the
+// .gitignore file.
+//
+// Blank lines and lines starting with '#' are ignored. Trailing spaces are
+// ignored unless they are escaped with '\'. '!' at the beginning negates
+// the pattern, and '\#' or '\!' at the beginning is matched literally.
+//
+// A pattern with a '/' at the beginning or in the middle is matched against
+// the whole path. Otherwise it is matched against the name at any depth.
+// A pattern with a '/' at the end matches only directories.
+// '*', '?', '[...]' and '**' are the same as in git.
+//
+// Like git, a path is ignored if one of its parent directories is ignored,
+// and negated patterns cannot include it again. Since Match is not told
+// whether a path is a directory, a pattern with a '/' at the end also
+// matches a file at the end of the path. The returned Matcher is an
+// EntryMatcher, and RecurReadDir uses MatchEntry to apply such patterns
+// only to directories.
+func ParseGitignore(r io.Reader) (Matcher, error) {
+	return parseGitignore(r)
+}
+
+func parseGitignore(r io.Reader) (*gitignoreMatcher, error) {
+	m := &gitignoreMatcher{}
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		r, err := parseGitignoreLine(s.Text(), len(m.rules))
+		if err != nil {
+			return nil, err
+		}
+		if r != nil {
+			r.line = line
+			m.rules = append(m.rules, r)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseGitignoreLine parses a line in the .gitignore format as the rule at
+// index. It returns nil for blank lines and comments.
+func parseGitignoreLine(line string, index int) (*rule, error) {
+	line = strings.TrimSuffix(line, "\r")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-len(" ")]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil, nil
+	}
+
+	r := &rule{source: line, index: index}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[len("!"):]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return nil, nil
+	}
+
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	pattern := convertGitignorePattern(line)
+	if strings.HasSuffix(pattern, "/**") {
+		// matches paths under the directory, but not the directory itself
+		pattern += "/*"
+	}
+	if !anchored {
+		pattern = "**/" + pattern
+	}
+
+	r.pattern = pattern
+	if gerr := r.compile(gitignoreOptions); gerr != nil {
+		// the offset in the converted pattern is not meaningful in line
+		return nil, &PatternError{Pattern: r.source, Index: index, Reason: gerr.reason}
+	}
+	return r, nil
+}
+
+// convertGitignorePattern converts a pattern in the .gitignore format to a
+// glob pattern of NewMatcher.
+func convertGitignorePattern(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); {
+		switch c := pattern[i]; c {
+		case '\\':
+			if i+1 == len(pattern) {
+				b.WriteByte(c)
+				i++
+				break
+			}
+			switch e := pattern[i+1]; e {
+			case '*', '?', '[':
+				b.WriteString("[" + string(e) + "]")
+			default:
+				b.WriteByte(e)
+			}
+			i += 2
+		case '[':
+			if _, n, gerr := parseBracket(pattern[i:]); gerr == nil {
+				b.WriteString(pattern[i : i+n])
+				i += n
+			} else {
+				b.WriteString("[[]")
+				i++
+			}
+		case '*':
+			n := len(pattern[i:]) - len(strings.TrimLeft(pattern[i:], "*"))
+			// '**' is special only as a whole path segment
+			if n > 1 && (i == 0 || pattern[i-1] == '/') &&
+				(i+n == len(pattern) || pattern[i+n] == '/') {
+				b.WriteString("**")
+			} else {
+				b.WriteString("*")
+			}
+			i += n
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+func (m *gitignoreMatcher) Match(path string) bool {
+	if strings.HasSuffix(path, "/") && len(path) > 1 {
+		return m.match(path[:len(path)-len("/")], true)
+	}
+	return m.match(path, true)
+}
+
+// MatchEntry is like Match, but directory-only patterns are applied to the
+// entry only if info tells it is a directory.
+func (m *gitignoreMatcher) MatchEntry(path string, info os.FileInfo) bool {
+	return m.match(path, info.IsDir())
+}
+
+func (m *gitignoreMatcher) match(path string, isDir bool) bool {
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && m.ignored(path[:i]) {
+			return false
+		}
+	}
+	r := m.lastMatch(path, isDir)
+	return r == nil || r.negate
+}
+
+// Explain returns the explanation of Match(path) with the last rule which
+// matched the path. If a parent directory of the path is ignored, the
+// rule which ignored the directory is returned as Exclude.
+func (m *gitignoreMatcher) Explain(path string) *Explanation {
+	e := &Explanation{Path: path}
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' {
+			if r := m.lastMatch(path[:i], true); r != nil && !r.negate {
+				r.explain(e)
+				return e
+			}
+		}
+	}
+	r := m.lastMatch(path, true)
+	e.Matched = r == nil || r.negate
+	r.explain(e)
+	return e
+}
+
+// Prune returns true if dir is ignored, since all paths under an ignored
+// directory are ignored.
+func (m *gitignoreMatcher) Prune(dir string) bool {
+	return m.ignored(dir)
+}
+
+// ignored returns true if the last rule matching path is not negated.
+// Directory-only rules are applied since path may be a directory.
+func (m *gitignoreMatcher) ignored(path string) bool {
+	r := m.lastMatch(path, true)
+	return r != nil && !r.negate
+}
+
+// lastMatch returns the last rule matching path, or nil if no rule
+// matches. Directory-only rules are skipped unless isDir is true.
+func (m *gitignoreMatcher) lastMatch(path string, isDir bool) *rule {
+	for i := len(m.rules) - 1; i >= 0; i-- {
+		r := m.rules[i]
+		if (isDir || !r.dirOnly) && r.set.match(path, isDir) {
+			return r
+		}
+	}
+	return nil
+}
+
+// loadIgnoreFile reads the ignore file in d if it is not read yet.
+func (w *Walker) loadIgnoreFile(d *walkDir) error {
+	if d.ignoreLoaded {
+		return nil
+	}
+	d.ignoreLoaded = true
+
+	data, err := w.r.readFileFunc(path.Join(d.dirname, w.r.ignoreFile))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	d.ignore, err = parseGitignore(bytes.NewReader(data))
+	return err
+}
+
+// ignored returns true if the entry at name in the directory on the top of
+// the stack is ignored by ignore files. Rules in the ignore file in a
+// deeper directory take precedence. Since ignored directories are not
+// walked, parent directories of the entry are not checked.
+func (w *Walker) ignored(name string, isDir bool) (bool, *WalkError) {
+	for i := len(w.stack) - 1; i >= 0; i-- {
+		d := w.stack[i]
+		if err := w.loadIgnoreFile(d); err != nil {
+			return false, &WalkError{path.Join(d.dirname, w.r.ignoreFile), err}
+		}
+		if d.ignore == nil {
+			continue
+		}
+		if r := d.ignore.lastMatch(relPath(d.dirname, name), isDir); r != nil {
+			return !r.negate, nil
+		}
+	}
+	return false, nil
+}
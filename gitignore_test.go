@@ -0,0 +1,207 @@
+package paths_test
+
+import (
+	. "github.com/hnakamur/paths"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func testGitignore(t *testing.T, gitignore string, cases []testCase) {
+	matcher, err := ParseGitignore(strings.NewReader(gitignore))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range cases {
+		actual := matcher.Match(c.path)
+		if actual != c.matches {
+			t.Errorf("path:%s\texpected:%v\tactual:%v", c.path, c.matches, actual)
+		}
+	}
+}
+
+func TestGitignore(t *testing.T) {
+	testGitignore(t, `
+# comment
+\#hash
+\!bang
+*.o
+!keep.o
+/root.txt
+doc/*.html
+build/
+foo/**
+!foo/bar
+**/logs/*.log
+a/**/b
+x**y
+\*star
+{a,b}
+`,
+		[]testCase{
+			{"# comment", true},
+			{"#hash", false},
+			{"!bang", false},
+			{"main.o", false},
+			{"src/main.o", false},
+			{"keep.o", true},
+			{"src/keep.o", true},
+			{"root.txt", false},
+			{"src/root.txt", true},
+			{"doc/index.html", false},
+			{"doc/api/index.html", true},
+			{"src/doc/index.html", true},
+			{"build", false},
+			{"build/main.go", false},
+			{"src/build/main.go", false},
+			{"foo", true},
+			{"foo/baz", false},
+			{"foo/bar", true},
+			{"foo/bar/baz", false},
+			{"logs/debug.log", false},
+			{"src/logs/debug.log", false},
+			{"logs/debug.txt", true},
+			{"a/b", false},
+			{"a/x/y/b", false},
+			{"xzzy", false},
+			{"x/y", true},
+			{"*star", false},
+			{"star", true},
+			{"{a,b}", false},
+			{"a", true},
+		})
+}
+
+func TestGitignoreTrailingSpaces(t *testing.T) {
+	testGitignore(t, "trailing   \r\nescaped\\ \n", []testCase{
+		{"trailing", false},
+		{"trailing   ", true},
+		{"escaped ", false},
+		{"escaped", true},
+	})
+}
+
+func TestGitignoreParentExcluded(t *testing.T) {
+	testGitignore(t, "build/\n!build/keep.txt\n", []testCase{
+		{"build/keep.txt", false},
+		{"build/other.txt", false},
+	})
+	testGitignore(t, "build/*\n!build/keep.txt\n", []testCase{
+		{"build", true},
+		{"build/keep.txt", true},
+		{"build/other.txt", false},
+	})
+}
+
+func TestGitignorePrune(t *testing.T) {
+	matcher, err := ParseGitignore(strings.NewReader("node_modules/\n/dist\n*.log\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pruner := matcher.(Pruner)
+	for _, c := range []testCase{
+		{"node_modules", true},
+		{"src/node_modules", true},
+		{"dist", true},
+		{"src/dist", false},
+		{"src", false},
+	} {
+		actual := pruner.Prune(c.path)
+		if actual != c.matches {
+			t.Errorf("path:%s\texpected:%v\tactual:%v", c.path, c.matches, actual)
+		}
+	}
+}
+
+func TestWalkerIgnoreFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"repo/.gitignore":            {Data: []byte("*.o\n/tmp/\nlogs/\n")},
+		"repo/main.c":                {},
+		"repo/main.o":                {},
+		"repo/tmp/a.txt":             {},
+		"repo/logs":                  {},
+		"repo/src/.gitignore":        {Data: []byte("!keep.o\ngen/\n*.txt\n")},
+		"repo/src/keep.o":            {},
+		"repo/src/lib.o":             {},
+		"repo/src/notes.txt":         {},
+		"repo/src/gen/x.c":           {},
+		"repo/src/tmp/b.c":           {},
+		"repo/src/logs/c.log":        {},
+		"repo/src/sub/.gitignore":    {Data: []byte("!*.txt\n")},
+		"repo/src/sub/readme.txt":    {},
+		"repo/src/sub/deep/more.txt": {},
+	}
+	var names []string
+	for fi, err := range All("repo", &ReadDirOptions{FS: fsys, IgnoreFile: ".gitignore"}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, fi.Name())
+	}
+
+	expected := []string{
+		"repo/.gitignore",
+		"repo/logs",
+		"repo/main.c",
+		"repo/src",
+		"repo/src/.gitignore",
+		"repo/src/keep.o",
+		"repo/src/sub",
+		"repo/src/sub/.gitignore",
+		"repo/src/sub/deep",
+		"repo/src/sub/deep/more.txt",
+		"repo/src/sub/readme.txt",
+		"repo/src/tmp",
+		"repo/src/tmp/b.c",
+	}
+	if strings.Join(names, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("names=%v\nexpected=%v", names, expected)
+	}
+}
+
+func TestGitignoreExplain(t *testing.T) {
+	matcher, err := ParseGitignore(strings.NewReader("# objects\n*.o\n\n!keep.o\nbuild/\n!build/keep.txt\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	explainer := matcher.(Explainer)
+
+	checkExplanation(t, explainer.Explain("main.c"), true, nil, nil)
+	checkExplanation(t, explainer.Explain("src/main.o"), false,
+		nil, &PatternMatch{Index: 0, Pattern: "*.o", Line: 2})
+	checkExplanation(t, explainer.Explain("src/keep.o"), true,
+		&PatternMatch{Index: 1, Pattern: "!keep.o", Line: 4}, nil)
+	checkExplanation(t, explainer.Explain("build/keep.txt"), false,
+		nil, &PatternMatch{Index: 2, Pattern: "build/", Line: 5})
+}
+
+func TestWalkerDirOnly(t *testing.T) {
+	fsys := fstest.MapFS{
+		"repo/logs":           {},
+		"repo/src/logs/c.log": {},
+		"repo/src/main.c":     {},
+	}
+	gitignore, err := ParseGitignore(strings.NewReader("logs/\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	matcher, err := NewMatcherWithOptions(nil, []string{"**/logs/"}, &MatcherOptions{DirOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"repo/logs", "repo/src", "repo/src/main.c"}
+	for _, m := range []Matcher{gitignore, matcher} {
+		var names []string
+		for fi, err := range All("repo", &ReadDirOptions{FS: fsys, Matcher: m}) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, fi.Name())
+		}
+		if strings.Join(names, " ") != strings.Join(expected, " ") {
+			t.Errorf("names=%v, expected=%v", names, expected)
+		}
+	}
+}
@@ -0,0 +1,534 @@
+package paths
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A PatternError describes a syntax error in a glob pattern.
+type PatternError struct {
+	Pattern string // the pattern as given
+	Index   int    // the index of the pattern in the given patterns
+	Offset  int    // the byte offset in Pattern where the error is found
+	Reason  string // the description of the error
+}
+
+func (e *PatternError) Error() string {
+	return fmt.Sprintf("paths: %s at offset %d in pattern #%d %q",
+		e.Reason, e.Offset, e.Index, e.Pattern)
+}
+
+// globError is a syntax error at offset in a string being parsed.
+type globError struct {
+	offset int
+	reason string
+}
+
+// glob is a glob pattern parsed after brace expansions.
+type glob struct {
+	leadingDirs bool      // '**/' at the beginning
+	segs        []globSeg // path segments separated by '/'
+	tree        bool      // '/' or '/**' at the end
+	dirOnly     bool      // '/' at the end matches only a directory
+}
+
+// globSeg is a path segment of a glob. It is either '**' which matches
+// zero or more directories, or a sequence of elements.
+type globSeg struct {
+	anyDirs bool
+	elems   []globElem
+}
+
+type globOp int
+
+const (
+	globLiteral globOp = iota // literal string
+	globStar                  // '*'
+	globAnyChar               // '?'
+	globClass                 // '[...]'
+)
+
+type globElem struct {
+	op     globOp
+	lit    string     // for globLiteral
+	class  *charClass // for globClass
+	offset int        // offset in the expansion
+}
+
+// parsePattern parses a glob pattern and returns globs for its brace
+// expansions.
+func parsePattern(pattern string, opts *MatcherOptions) ([]*glob, *globError) {
+	for i, r := range pattern {
+		if r == utf8.RuneError {
+			if _, n := utf8.DecodeRuneInString(pattern[i:]); n == 1 {
+				return nil, &globError{i, "invalid UTF-8"}
+			}
+		}
+	}
+	exps, gerr := expandBraces(pattern, opts)
+	if gerr != nil {
+		return nil, gerr
+	}
+	globs := make([]*glob, len(exps))
+	for i, exp := range exps {
+		g, gerr := parseGlob(exp.text, opts)
+		if gerr != nil {
+			gerr.offset = exp.offset(gerr.offset)
+			return nil, gerr
+		}
+		globs[i] = g
+	}
+	return globs, nil
+}
+
+// parseGlob parses a glob pattern without braces to be expanded.
+//
+// '**' must be a whole path segment. '**/' at the beginning or '/**/' in
+// the middle matches zero or more directories. '/' or '/**' at the end
+// matches the path and any paths under it. Other '**', like '**' as the
+// whole pattern or '**' right after '**/', matches a name like '*'.
+func parseGlob(pattern string, opts *MatcherOptions) (*glob, *globError) {
+	segs := [][]globElem{nil}
+	for i := 0; i < len(pattern); {
+		last := &segs[len(segs)-1]
+		switch c := pattern[i]; {
+		case c == '/':
+			segs = append(segs, nil)
+			i++
+		case c == '*':
+			*last = append(*last, globElem{op: globStar, offset: i})
+			i++
+		case c == '?':
+			*last = append(*last, globElem{op: globAnyChar, offset: i})
+			i++
+		case c == '[' && !opts.LiteralBrackets:
+			class, n, gerr := parseBracket(pattern[i:])
+			if gerr != nil {
+				gerr.offset += i
+				return nil, gerr
+			}
+			*last = append(*last, globElem{op: globClass, class: class, offset: i})
+			i += n
+		default:
+			_, n := utf8.DecodeRuneInString(pattern[i:])
+			if k := len(*last) - 1; k >= 0 && (*last)[k].op == globLiteral {
+				(*last)[k].lit += pattern[i : i+n]
+			} else {
+				*last = append(*last, globElem{op: globLiteral, lit: pattern[i : i+n], offset: i})
+			}
+			i += n
+		}
+	}
+
+	g := &glob{}
+	for _, elems := range segs {
+		seg, gerr := newGlobSeg(elems)
+		if gerr != nil {
+			return nil, gerr
+		}
+		g.segs = append(g.segs, seg)
+	}
+	if len(g.segs) > 1 && g.segs[0].anyDirs {
+		g.leadingDirs = true
+		g.segs = g.segs[1:]
+	}
+	if n := len(g.segs); n > 1 && (g.segs[n-1].anyDirs || len(g.segs[n-1].elems) == 0) {
+		g.tree = true
+		g.dirOnly = opts.DirOnly && !g.segs[n-1].anyDirs
+		g.segs = g.segs[:n-1]
+	}
+	// '**' right after '**/' matches a name like '*' as in the older
+	// versions, which replaced only every other '/**/' in a row
+	prevAnyDirs := g.leadingDirs
+	for i := range g.segs {
+		if g.segs[i].anyDirs && prevAnyDirs {
+			g.segs[i] = globSeg{elems: []globElem{{op: globStar}}}
+		}
+		prevAnyDirs = g.segs[i].anyDirs
+	}
+	if n := len(g.segs); g.segs[n-1].anyDirs {
+		// '**' at the end matches a name like '*' as in the older versions
+		g.segs[n-1] = globSeg{elems: []globElem{{op: globStar}}}
+	}
+	return g, nil
+}
+
+// newGlobSeg returns a segment for elems. Two '*' elements as the whole
+// segment are '**', and they are invalid in other places.
+func newGlobSeg(elems []globElem) (globSeg, *globError) {
+	if len(elems) == 2 && elems[0].op == globStar && elems[1].op == globStar {
+		return globSeg{anyDirs: true}, nil
+	}
+	for i := 1; i < len(elems); i++ {
+		if elems[i-1].op == globStar && elems[i].op == globStar {
+			return globSeg{}, &globError{elems[i-1].offset, "'**' must be a whole path segment"}
+		}
+	}
+	return globSeg{elems: elems}, nil
+}
+
+// isLiteral returns true if the segment has no wildcards.
+func (s *globSeg) isLiteral() bool {
+	return !s.anyDirs && (len(s.elems) == 0 ||
+		len(s.elems) == 1 && s.elems[0].op == globLiteral)
+}
+
+// literal returns the string of a literal segment.
+func (s *globSeg) literal() string {
+	if len(s.elems) == 0 {
+		return ""
+	}
+	return s.elems[0].lit
+}
+
+// treeGlobs returns globs which end with '/' or '/**'.
+// Such a glob matches a directory and all paths under it.
+func treeGlobs(globs []*glob) []*glob {
+	var trees []*glob
+	for _, g := range globs {
+		if g.tree {
+			trees = append(trees, g)
+		}
+	}
+	return trees
+}
+
+// fileGlobs returns globs for files. A glob which matches only directories
+// is replaced with a glob which matches paths under the directories.
+func fileGlobs(globs []*glob) []*glob {
+	files := make([]*glob, len(globs))
+	for i, g := range globs {
+		if g.dirOnly {
+			f := *g
+			f.segs = append(append([]globSeg(nil), g.segs...), globSeg{anyDirs: true})
+			f.tree = false
+			f.dirOnly = false
+			g = &f
+		}
+		files[i] = g
+	}
+	return files
+}
+
+func hasDirOnly(globs []*glob) bool {
+	for _, g := range globs {
+		if g.dirOnly {
+			return true
+		}
+	}
+	return false
+}
+
+// mayMatchUnder returns false if g matches no path under dir.
+// It may return true even if g matches no path under dir.
+func (g *glob) mayMatchUnder(dir string, opts *MatcherOptions) bool {
+	if g.leadingDirs {
+		return true
+	}
+	for i, dirSeg := range strings.Split(dir, "/") {
+		if i == len(g.segs) {
+			// g matches dir or its ancestor
+			return g.tree
+		}
+		seg := &g.segs[i]
+		if !seg.isLiteral() {
+			return true
+		}
+		if lit := seg.literal(); lit != dirSeg &&
+			!(opts.CaseInsensitive && strings.EqualFold(lit, dirSeg)) {
+			return false
+		}
+	}
+	return true
+}
+
+// maxBraceExpansions is the maximum number of patterns expanded from a
+// pattern with braces.
+const maxBraceExpansions = 10000
+
+// expansion is a pattern expanded from braces.
+type expansion struct {
+	text    string
+	offsets []int // offsets[i] is the offset of text[i] in the pattern
+	end     int   // the offset of the end of text in the pattern
+}
+
+// offset returns the offset in the pattern for the offset i in text.
+func (e *expansion) offset(i int) int {
+	if i < len(e.offsets) {
+		return e.offsets[i]
+	}
+	return e.end
+}
+
+func (e *expansion) slice(i, j int) expansion {
+	end := e.end
+	if j < len(e.offsets) {
+		end = e.offsets[j]
+	}
+	return expansion{e.text[i:j], e.offsets[i:j], end}
+}
+
+func concatExpansions(exps ...expansion) expansion {
+	var c expansion
+	for _, e := range exps {
+		c.text += e.text
+		c.offsets = append(c.offsets, e.offsets...)
+		c.end = e.end
+	}
+	return c
+}
+
+// expandBraces expands brace expressions in a glob pattern.
+//
+// '{a,b,c}' is expanded to 'a', 'b' and 'c', and braces can be nested like
+// '{a,b{c,d}}'. '{1..12}' is expanded to numbers from 1 to 12, and
+// '{01..12}' is expanded to numbers padded with zeros to the same width.
+// '{' without the matching '}', and braces without ',' nor '..' are
+// matched literally. Braces in bracket expressions are not expanded.
+func expandBraces(pattern string, opts *MatcherOptions) ([]expansion, *globError) {
+	exp := expansion{pattern, make([]int, len(pattern)), len(pattern)}
+	for i := range exp.offsets {
+		exp.offsets[i] = i
+	}
+	if opts.LiteralBraces {
+		return []expansion{exp}, nil
+	}
+	var exps []expansion
+	gerr := appendBraceExpansions(&exps, exp, opts)
+	return exps, gerr
+}
+
+func appendBraceExpansions(exps *[]expansion, exp expansion, opts *MatcherOptions) *globError {
+	pattern := exp.text
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '[':
+			if !opts.LiteralBrackets {
+				if _, n, gerr := parseBracket(pattern[i:]); gerr == nil {
+					i += n - 1
+				}
+			}
+		case '{':
+			alts, n := parseBraces(exp.slice(i, len(pattern)), opts)
+			if alts == nil {
+				continue
+			}
+			prefix, suffix := exp.slice(0, i), exp.slice(i+n, len(pattern))
+			for _, alt := range alts {
+				gerr := appendBraceExpansions(exps, concatExpansions(prefix, alt, suffix), opts)
+				if gerr != nil {
+					return gerr
+				}
+			}
+			return nil
+		}
+	}
+	if len(*exps) >= maxBraceExpansions {
+		return &globError{0, "too many brace expansions"}
+	}
+	*exps = append(*exps, exp)
+	return nil
+}
+
+// parseBraces parses braces at the beginning of exp and returns the
+// alternatives and the length of the braces. It returns nil if exp does
+// not start with braces to be expanded.
+func parseBraces(exp expansion, opts *MatcherOptions) ([]expansion, int) {
+	s := exp.text
+	var alts []expansion
+	depth := 0
+	start := len("{")
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			if !opts.LiteralBrackets {
+				if _, n, gerr := parseBracket(s[i:]); gerr == nil {
+					i += n - 1
+				}
+			}
+		case '{':
+			depth++
+		case ',':
+			if depth == 1 {
+				alts = append(alts, exp.slice(start, i))
+				start = i + len(",")
+			}
+		case '}':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			if alts != nil {
+				return append(alts, exp.slice(start, i)), i + len("}")
+			}
+			nums := numericRange(s[start:i])
+			if nums == nil {
+				return nil, 0
+			}
+			for _, num := range nums {
+				offsets := make([]int, len(num))
+				for j := range offsets {
+					offsets[j] = exp.offset(0)
+				}
+				alts = append(alts, expansion{num, offsets, exp.offset(0)})
+			}
+			return alts, i + len("}")
+		}
+	}
+	return nil, 0
+}
+
+// maxRangeWidth is the maximum length of the numbers in a numeric range.
+const maxRangeWidth = 20
+
+// numericRange expands a range like '1..12' or '01..12'. It returns nil if
+// s is not a range, or the range or the numbers are too large.
+func numericRange(s string) []string {
+	first, last, ok := strings.Cut(s, "..")
+	if !ok || len(first) > maxRangeWidth || len(last) > maxRangeWidth {
+		return nil
+	}
+	lo, err := strconv.Atoi(first)
+	if err != nil {
+		return nil
+	}
+	hi, err := strconv.Atoi(last)
+	if err != nil {
+		return nil
+	}
+	// the span is computed in uint64 since hi-lo may overflow int
+	step := 1
+	span := uint64(hi) - uint64(lo)
+	if hi < lo {
+		step = -1
+		span = uint64(lo) - uint64(hi)
+	}
+	if span >= maxBraceExpansions {
+		return nil
+	}
+
+	width := 0
+	if hasLeadingZero(first) || hasLeadingZero(last) {
+		width = max(len(first), len(last))
+	}
+	var nums []string
+	for n := lo; ; n += step {
+		nums = append(nums, fmt.Sprintf("%0*d", width, n))
+		if n == hi {
+			break
+		}
+	}
+	return nums
+}
+
+func hasLeadingZero(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	return len(s) > 1 && s[0] == '0'
+}
+
+// runeRange is a range of runes from lo to hi inclusive.
+type runeRange struct {
+	lo, hi rune
+}
+
+// charClass is a bracket expression. It never matches '/'.
+type charClass struct {
+	negate bool
+	ranges []runeRange
+}
+
+// parseBracket parses a bracket expression at the beginning of s and
+// returns the class and the length of the expression.
+//
+// '!' or '^' after '[' negates the class. ']' right after '[', '[!' or
+// '[^' and a character escaped with '\' are matched literally.
+// 'a-z' is a range of characters and '[:alpha:]' is a character class
+// of POSIX.
+func parseBracket(s string) (*charClass, int, *globError) {
+	class := &charClass{}
+	i := len("[")
+	if i < len(s) && (s[i] == '!' || s[i] == '^') {
+		class.negate = true
+		i++
+	}
+
+	for first := true; ; first = false {
+		if i >= len(s) {
+			return nil, 0, &globError{0, errUnterminatedBracket}
+		}
+		if s[i] == ']' && !first {
+			i++
+			break
+		}
+
+		if strings.HasPrefix(s[i:], "[:") {
+			end := strings.Index(s[i+len("[:"):], ":]")
+			if end >= 0 {
+				name := s[i+len("[:") : i+len("[:")+end]
+				ranges, ok := posixClasses[name]
+				if !ok {
+					return nil, 0, &globError{i, fmt.Sprintf("unknown character class %q", name)}
+				}
+				class.ranges = append(class.ranges, ranges...)
+				i += len("[:") + end + len(":]")
+				continue
+			}
+		}
+
+		start := i
+		lo, n, ok := bracketRune(s[i:])
+		if !ok {
+			return nil, 0, &globError{0, errUnterminatedBracket}
+		}
+		i += n
+		hi := lo
+		if strings.HasPrefix(s[i:], "-") && !strings.HasPrefix(s[i:], "-]") {
+			hi, n, ok = bracketRune(s[i+len("-"):])
+			if !ok {
+				return nil, 0, &globError{0, errUnterminatedBracket}
+			}
+			if hi < lo {
+				return nil, 0, &globError{start, fmt.Sprintf("invalid range %q", s[start:i+len("-")+n])}
+			}
+			i += len("-") + n
+		}
+		class.ranges = append(class.ranges, runeRange{lo, hi})
+	}
+	return class, i, nil
+}
+
+const errUnterminatedBracket = "unterminated bracket expression"
+
+// bracketRune returns a possibly escaped rune at the beginning of s and
+// its length. It returns false if s is empty or only has '\'.
+func bracketRune(s string) (rune, int, bool) {
+	n := 0
+	if strings.HasPrefix(s, `\`) {
+		n = len(`\`)
+	}
+	if n >= len(s) {
+		return 0, 0, false
+	}
+	r, size := utf8.DecodeRuneInString(s[n:])
+	return r, n + size, true
+}
+
+var posixClasses = map[string][]runeRange{
+	"alnum":  {{'0', '9'}, {'A', 'Z'}, {'a', 'z'}},
+	"alpha":  {{'A', 'Z'}, {'a', 'z'}},
+	"ascii":  {{0, 0x7f}},
+	"blank":  {{'\t', '\t'}, {' ', ' '}},
+	"cntrl":  {{0, 0x1f}, {0x7f, 0x7f}},
+	"digit":  {{'0', '9'}},
+	"graph":  {{'!', '~'}},
+	"lower":  {{'a', 'z'}},
+	"print":  {{' ', '~'}},
+	"punct":  {{'!', '/'}, {':', '@'}, {'[', '`'}, {'{', '~'}},
+	"space":  {{'\t', '\r'}, {' ', ' '}},
+	"upper":  {{'A', 'Z'}},
+	"word":   {{'0', '9'}, {'A', 'Z'}, {'_', '_'}, {'a', 'z'}},
+	"xdigit": {{'0', '9'}, {'A', 'F'}, {'a', 'f'}},
+}
@@ -0,0 +1,394 @@
+package paths
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// pathSet is a set of paths which match globs. It is implemented by
+// *globSet, and by *regexp.Regexp in tests.
+type pathSet interface {
+	MatchString(path string) bool
+}
+
+// newPathSetFunc returns a pathSet for globs, or nil if globs is empty.
+type newPathSetFunc func(globs []*glob, opts *MatcherOptions) pathSet
+
+// entrySet is a pair of pathSets for files and directories. They differ
+// only if some globs match only directories.
+type entrySet struct {
+	file pathSet
+	dir  pathSet
+}
+
+// newEntrySet returns an entrySet for globs, or nil if globs is empty.
+func newEntrySet(globs []*glob, opts *MatcherOptions, newSet newPathSetFunc) *entrySet {
+	if len(globs) == 0 {
+		return nil
+	}
+	s := &entrySet{dir: newSet(globs, opts)}
+	s.file = s.dir
+	if hasDirOnly(globs) {
+		s.file = newSet(fileGlobs(globs), opts)
+	}
+	return s
+}
+
+func (s *entrySet) match(path string, isDir bool) bool {
+	if isDir {
+		return s.dir.MatchString(path)
+	}
+	return s.file.MatchString(path)
+}
+
+// globSet matches paths against globs segment by segment without regular
+// expressions. Globs of common forms are looked up in hash sets.
+type globSet struct {
+	fold bool
+
+	exact     map[string]struct{} // literal paths like 'a/b'
+	exactTree map[string]struct{} // literal paths and under them like 'a/b/'
+	names     map[string]struct{} // names at any depth like '**/a'
+	nameTrees map[string]struct{} // names at any depth and under them like '**/a/'
+	exts      map[string]struct{} // extensions at any depth like '**/*.go'
+
+	firsts map[string][]*glob // the other globs by literal first segments
+	globs  []*glob            // the other globs
+}
+
+// newGlobSet returns a globSet for globs, or nil if globs is empty.
+func newGlobSet(globs []*glob, opts *MatcherOptions) pathSet {
+	if len(globs) == 0 {
+		return nil
+	}
+
+	s := &globSet{fold: opts.CaseInsensitive}
+	for _, g := range globs {
+		switch {
+		case !g.leadingDirs && g.isLiteral():
+			lit := s.key(g.literal())
+			if g.tree {
+				addKey(&s.exactTree, lit)
+			} else {
+				addKey(&s.exact, lit)
+			}
+		case g.leadingDirs && len(g.segs) == 1 && g.segs[0].isLiteral():
+			name := s.key(g.segs[0].literal())
+			if g.tree {
+				addKey(&s.nameTrees, name)
+			} else {
+				addKey(&s.names, name)
+			}
+		case g.leadingDirs && len(g.segs) == 1 && !g.tree && g.segs[0].ext() != "":
+			addKey(&s.exts, s.key(g.segs[0].ext()))
+		case !g.leadingDirs && g.segs[0].isLiteral():
+			first := s.key(g.segs[0].literal())
+			if s.firsts == nil {
+				s.firsts = make(map[string][]*glob)
+			}
+			s.firsts[first] = append(s.firsts[first], g)
+		default:
+			s.globs = append(s.globs, g)
+		}
+	}
+	return s
+}
+
+func addKey(m *map[string]struct{}, key string) {
+	if *m == nil {
+		*m = make(map[string]struct{})
+	}
+	(*m)[key] = struct{}{}
+}
+
+// key returns s folded if the set is case-insensitive.
+func (s *globSet) key(str string) string {
+	if !s.fold {
+		return str
+	}
+	return strings.Map(foldRune, str)
+}
+
+func (s *globSet) MatchString(path string) bool {
+	key := s.key(path)
+	if _, ok := s.exact[key]; ok {
+		return true
+	}
+	if s.exactTree != nil {
+		if _, ok := s.exactTree[key]; ok {
+			return true
+		}
+		for i := 0; i < len(key); i++ {
+			if key[i] != '/' || i+1 == len(key) {
+				continue
+			}
+			if _, ok := s.exactTree[key[:i]]; ok {
+				return true
+			}
+		}
+	}
+
+	segs := strings.Split(key, "/")
+	if s.names != nil || s.exts != nil {
+		if i := len(segs) - 1; canSkipDirs(segs[:i]) {
+			if _, ok := s.names[segs[i]]; ok {
+				return true
+			}
+			if s.matchExt(segs[i]) {
+				return true
+			}
+		}
+	}
+	if s.nameTrees != nil {
+		for i, seg := range segs {
+			if !canSkipDirs(segs[:i]) || !isTreeRest(segs[i+1:]) {
+				continue
+			}
+			if _, ok := s.nameTrees[seg]; ok {
+				return true
+			}
+		}
+	}
+
+	globs := s.firsts[segs[0]]
+	if len(globs) > 0 || len(s.globs) > 0 {
+		// the globs are matched in the original case
+		segs = strings.Split(path, "/")
+		for _, g := range globs {
+			if g.match(segs, s.fold) {
+				return true
+			}
+		}
+		for _, g := range s.globs {
+			if g.match(segs, s.fold) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchExt returns true if name ends with one of the extensions.
+func (s *globSet) matchExt(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if name[i] != '.' {
+			continue
+		}
+		if _, ok := s.exts[name[i:]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// canSkipDirs returns true if '**/' matches segs joined with '/' and
+// followed by '/'. '(.+/)?' does not match a single '/'.
+func canSkipDirs(segs []string) bool {
+	return len(segs) != 1 || segs[0] != ""
+}
+
+// isTreeRest returns true if '(/.+)?' matches segs joined with '/' and
+// preceded by '/'.
+func isTreeRest(segs []string) bool {
+	return len(segs) != 1 || segs[0] != ""
+}
+
+// isLiteral returns true if the glob has no wildcards.
+func (g *glob) isLiteral() bool {
+	for i := range g.segs {
+		if !g.segs[i].isLiteral() {
+			return false
+		}
+	}
+	return true
+}
+
+// literal returns the path of a literal glob.
+func (g *glob) literal() string {
+	lits := make([]string, len(g.segs))
+	for i := range g.segs {
+		lits[i] = g.segs[i].literal()
+	}
+	return strings.Join(lits, "/")
+}
+
+// ext returns the extension if the segment is '*' followed by a literal
+// starting with '.', or an empty string otherwise.
+func (s *globSeg) ext() string {
+	if len(s.elems) != 2 || s.elems[0].op != globStar ||
+		s.elems[1].op != globLiteral || !strings.HasPrefix(s.elems[1].lit, ".") {
+		return ""
+	}
+	return s.elems[1].lit
+}
+
+// match returns true if the glob matches the path split into segs.
+func (g *glob) match(segs []string, fold bool) bool {
+	m := segsMatcher{gsegs: g.segs, segs: segs, tree: g.tree, fold: fold}
+	if g.leadingDirs {
+		return m.matchAnyDirs(0, 0)
+	}
+	return m.matchSegs(0, 0)
+}
+
+// segsMatcher matches glob segments against path segments. Positions where
+// '**' failed to match are remembered, so each pair of positions is tried
+// at most once, and globs with many '**' do not take exponential time.
+type segsMatcher struct {
+	gsegs []globSeg
+	segs  []string
+	tree  bool
+	fold  bool
+
+	depth  int    // nesting of matchAnyDirs
+	failed []bool // by gi*(len(segs)+1)+si, allocated for nested '**'
+}
+
+// matchSegs returns true if gsegs[gi:] match segs[si:]. If tree is true,
+// gsegs may match the leading segments of segs.
+func (m *segsMatcher) matchSegs(gi, si int) bool {
+	for ; gi < len(m.gsegs); gi, si = gi+1, si+1 {
+		gseg := &m.gsegs[gi]
+		if gseg.anyDirs {
+			if gi == len(m.gsegs)-1 {
+				// '**' at the end matches one or more characters
+				rest := m.segs[si:]
+				return len(rest) > 0 && (len(rest) > 1 || rest[0] != "")
+			}
+			return m.matchAnyDirs(gi+1, si)
+		}
+		if si == len(m.segs) || !gseg.match(m.segs[si], m.fold) {
+			return false
+		}
+	}
+	rest := m.segs[si:]
+	return len(rest) == 0 || m.tree && isTreeRest(rest)
+}
+
+// matchAnyDirs returns true if gsegs[gi:] match segs[si:] after skipping
+// zero or more directories.
+func (m *segsMatcher) matchAnyDirs(gi, si int) bool {
+	k := gi*(len(m.segs)+1) + si
+	if m.failed != nil && m.failed[k] {
+		return false
+	}
+	m.depth++
+	for i := si; i < len(m.segs); i++ {
+		if canSkipDirs(m.segs[si:i]) && m.matchSegs(gi, i) {
+			m.depth--
+			return true
+		}
+	}
+	m.depth--
+	if m.depth > 0 {
+		// only nested calls can be made again for the same positions
+		if m.failed == nil {
+			m.failed = make([]bool, (len(m.gsegs)+1)*(len(m.segs)+1))
+		}
+		m.failed[k] = true
+	}
+	return false
+}
+
+// match returns true if the segment matches name which has no '/'.
+func (s *globSeg) match(name string, fold bool) bool {
+	elems := s.elems
+	i, j := 0, 0
+	star, starJ := -1, 0
+	for {
+		if i < len(elems) {
+			e := &elems[i]
+			if e.op == globStar {
+				star, starJ = i, j
+				i++
+				continue
+			}
+			if n := e.matchPrefix(name[j:], fold); n > 0 {
+				i++
+				j += n
+				continue
+			}
+		} else if j == len(name) {
+			return true
+		}
+
+		// let the last '*' match one more character
+		if star < 0 || starJ == len(name) {
+			return false
+		}
+		_, n := utf8.DecodeRuneInString(name[starJ:])
+		starJ += n
+		i, j = star+1, starJ
+	}
+}
+
+// matchPrefix returns the length of the prefix of s which the element
+// matches, or 0 if it does not match. The element must not be '*'.
+func (e *globElem) matchPrefix(s string, fold bool) int {
+	switch e.op {
+	case globLiteral:
+		return matchLiteral(e.lit, s, fold)
+	case globAnyChar:
+		_, n := utf8.DecodeRuneInString(s)
+		return n
+	case globClass:
+		if s == "" {
+			return 0
+		}
+		r, n := utf8.DecodeRuneInString(s)
+		if !e.class.match(r, fold) {
+			return 0
+		}
+		return n
+	}
+	return 0
+}
+
+// matchLiteral returns the length of the prefix of s which matches lit, or
+// 0 if s does not start with lit.
+func matchLiteral(lit, s string, fold bool) int {
+	if !fold {
+		if strings.HasPrefix(s, lit) {
+			return len(lit)
+		}
+		return 0
+	}
+
+	n := 0
+	for _, lr := range lit {
+		if n == len(s) {
+			return 0
+		}
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if r != lr && foldRune(r) != foldRune(lr) {
+			return 0
+		}
+		n += size
+	}
+	return n
+}
+
+// match returns true if the class matches r. If fold is true, r matches
+// when a rune equivalent to r under the case folding is in the ranges.
+func (c *charClass) match(r rune, fold bool) bool {
+	if r == '/' {
+		return false
+	}
+	in := c.contains(r)
+	if fold {
+		for f := unicode.SimpleFold(r); !in && f != r; f = unicode.SimpleFold(f) {
+			in = c.contains(f)
+		}
+	}
+	return in != c.negate
+}
+
+func (c *charClass) contains(r rune) bool {
+	for _, rr := range c.ranges {
+		if rr.lo <= r && r <= rr.hi {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package paths
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var globSetTestPatterns = append([]string{
+	"", "/", "**", "**/", "**/**", "a/**/**",
+	"*.go", "**/*.go", "**/*.tar.gz", "**/*.TXT", "*~",
+	"src/", "src/**", "src/**/*.go", "/src/*", "a/*/c", "a?c", "a/**/b/**/c",
+	"**/[a-c]*.txt", "[!.]*", "**/[[:upper:]]*", "[^k]", "**/x[-/]y",
+	"{src,docs}/", "**/*.{go,mod,sum}", "file{1..3}.txt",
+	"K", "**/K*", "**/é/", "a/\n/b",
+}, DefaultExcludes...)
+
+var globSetTestPaths = []string{
+	"", "/", "//", "/a", "a/", "a//b", "a", "b", "c", "K", "k", "K",
+	"a.go", "A.GO", "x/a.go", "/x/a.go", "x/a.gox", ".go", "x.tar.gz", "x.gz",
+	"a.txt", "A.TXT", "x/b.TXT", "x/b.txt", "x/d.txt", "a~", "x/a~",
+	"src", "SRC", "src/", "src/a.go", "src/x/y.go", "/src/a", "docs/x",
+	"abc", "aXc", "a/b/c", "a/x/y/c", "a/b/x/c", "a/c", "a/b", "a/b/d/c",
+	"x-y", "x/x-y", "x/x/y", "file1.txt", "file4.txt", ".hidden", "é/x", "x/É/y",
+	".git", "x/.git", "x/.git/", "/.git/x", "x/.git/config", ".git/HEAD",
+	"a/\n/b", "a\nb/.svn/x", "x/#a#", "x/._a", "CVS", "x/cvs/y",
+}
+
+func TestGlobSetSameAsRegexp(t *testing.T) {
+	for _, opts := range []*MatcherOptions{{}, {CaseInsensitive: true}, {DirOnly: true}} {
+		globs, err := parsePatterns(globSetTestPatterns, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		flat := flattenGlobs(globs)
+		for i, g := range append(globs, flat, fileGlobs(flat)) {
+			set := newGlobSet(g, opts)
+			re := regexpSet(g, opts)
+			for _, p := range globSetTestPaths {
+				if got, want := set.MatchString(p), re.MatchString(p); got != want {
+					t.Errorf("pattern #%d, fold=%v, path=%q: got=%v, expected=%v",
+						i, opts.CaseInsensitive, p, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestGlobSetSameAsOlderVersions(t *testing.T) {
+	opts := &MatcherOptions{LiteralBrackets: true, LiteralBraces: true}
+	for _, pattern := range append([]string{
+		"a/**/**/b", "**/**/a", "a/**/**/**/b", "**/**/**/a", "a/**/**/**",
+		"a/**/**/**/**", "**/**/", "a/**/b/**/**/c", "a/**/*/**/b",
+	}, globSetTestPatterns...) {
+		globs, err := parsePatterns([]string{pattern}, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		set := newGlobSet(flattenGlobs(globs), opts)
+		re := olderRegexp([]string{pattern})
+		for _, p := range append([]string{"a//b", "a/x/b", "a/x/y/b", "/a", "x/y/a"}, globSetTestPaths...) {
+			if strings.Contains(p, "\n") {
+				// '.' in the older regular expressions does not match '\n'
+				continue
+			}
+			if got, want := set.MatchString(p), re.MatchString(p); got != want {
+				t.Errorf("pattern=%q, path=%q: got=%v, expected=%v", pattern, p, got, want)
+			}
+		}
+	}
+}
+
+func TestGlobMatcherSameAsRegexp(t *testing.T) {
+	includes := []string{"**/*.go", "src/", "a/**/b/**/c"}
+	glob, err := newGlobMatcher(includes, DefaultExcludes, nil, newGlobSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	re, err := newGlobMatcher(includes, DefaultExcludes, nil, regexpSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range globSetTestPaths {
+		if glob.Match(p) != re.Match(p) {
+			t.Errorf("Match(%q)=%v, expected=%v", p, glob.Match(p), re.Match(p))
+		}
+		if glob.Prune(p) != re.Prune(p) {
+			t.Errorf("Prune(%q)=%v, expected=%v", p, glob.Prune(p), re.Prune(p))
+		}
+	}
+}
+
+// TestGlobSetManyDoubleStars times out if '**' is matched by backtracking
+// in exponential time.
+func TestGlobSetManyDoubleStars(t *testing.T) {
+	globs, err := parsePatterns([]string{strings.Repeat("**/a/", 16) + "zzz"}, &MatcherOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := newGlobSet(flattenGlobs(globs), &MatcherOptions{})
+	for _, c := range []struct {
+		path     string
+		expected bool
+	}{
+		{strings.Repeat("a/", 80) + "a", false},
+		{strings.Repeat("a/", 80) + "zzz", true},
+	} {
+		if got := set.MatchString(c.path); got != c.expected {
+			t.Errorf("MatchString(%q)=%v, expected=%v", c.path, got, c.expected)
+		}
+	}
+}
+
+// benchmarkPatterns returns n patterns like CODEOWNERS files.
+func benchmarkPatterns(n int) []string {
+	patterns := make([]string, 0, n)
+	for i := 0; len(patterns) < n; i++ {
+		patterns = append(patterns,
+			fmt.Sprintf("pkg%d/module%d/file%d.go", i%50, i%7, i),
+			fmt.Sprintf("**/*.ext%d", i),
+			fmt.Sprintf("**/dir%d/", i),
+			fmt.Sprintf("src%d/**/*_test%d.go", i%20, i),
+		)
+	}
+	return patterns[:n]
+}
+
+var benchmarkPaths = []string{
+	"pkg3/module3/file3.go",
+	"pkg3/module3/file4.go",
+	"a/b/c/d/e.ext500",
+	"a/b/c/d/e.go",
+	"x/y/dir999/z",
+	"src5/x/y/a_test5.go",
+	"src5/x/y/a_test6.go",
+}
+
+func benchmarkMatch(b *testing.B, newSet newPathSetFunc, n int) {
+	m, err := newGlobMatcher(nil, benchmarkPatterns(n), nil, newSet)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, p := range benchmarkPaths {
+			m.Match(p)
+		}
+	}
+}
+
+func benchmarkNewMatcher(b *testing.B, newSet newPathSetFunc, n int) {
+	patterns := benchmarkPatterns(n)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := newGlobMatcher(nil, patterns, nil, newSet); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMatch(b *testing.B) {
+	for _, n := range []int{10, 1000} {
+		b.Run(fmt.Sprintf("glob/%d", n), func(b *testing.B) { benchmarkMatch(b, newGlobSet, n) })
+		b.Run(fmt.Sprintf("regexp/%d", n), func(b *testing.B) { benchmarkMatch(b, regexpSet, n) })
+	}
+}
+
+func BenchmarkNewMatcher(b *testing.B) {
+	for _, n := range []int{10, 1000} {
+		b.Run(fmt.Sprintf("glob/%d", n), func(b *testing.B) { benchmarkNewMatcher(b, newGlobSet, n) })
+		b.Run(fmt.Sprintf("regexp/%d", n), func(b *testing.B) { benchmarkNewMatcher(b, regexpSet, n) })
+	}
+}
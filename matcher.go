@@ -1,17 +1,42 @@
 package paths
 
 import (
-	"regexp"
+	"os"
 	"strings"
+	"sync"
 )
 
 type Matcher interface {
 	Match(path string) bool
 }
 
-type matcherRegexp struct {
-	include *regexp.Regexp
-	exclude *regexp.Regexp
+// A Pruner is a Matcher which can tell that no path under a directory
+// matches. RecurReadDir does not read such directories.
+type Pruner interface {
+	Matcher
+
+	// Prune returns true if no path under dir matches.
+	// dir itself may still match.
+	Prune(dir string) bool
+}
+
+type globMatcher struct {
+	include *entrySet
+	exclude *entrySet
+
+	// excludeTree matches directories whose descendants are all excluded.
+	excludeTree pathSet
+
+	// patterns as given, parsed and their sets for Explain
+	includePatterns []string
+	excludePatterns []string
+	includeGlobs    [][]*glob
+	excludeGlobs    [][]*glob
+	opts            *MatcherOptions
+	newSet          newPathSetFunc
+	explainOnce     sync.Once
+	includeSets     []*entrySet
+	excludeSets     []*entrySet
 }
 
 // NewMatcher returns a new Matcher for include and exclude glob patterns.
@@ -24,26 +49,168 @@ type matcherRegexp struct {
 // '*' matches zero or more characters, '?' matches one character.
 // '*' and '?' does not match a directory separator '/'.
 //
+// '[...]' matches one character in the bracket expression, like '[a-z]',
+// '[!.]' or '[[:digit:]]'. '!' or '^' after '[' negates the expression.
+// ']' at the beginning and a character escaped by '\' in the expression
+// are matched literally. A bracket expression does not match '/'.
+//
+// '{a,b}' matches 'a' or 'b', and braces can be nested like '{a,b{c,d}}'.
+// '{1..12}' matches numbers from 1 to 12, and '{01..12}' matches numbers
+// padded with zeros like '01' to '12'. '{' without the matching '}' and
+// braces without ',' nor '..' are matched literally.
+//
 // '**/' at the beginning or '/**/' in the middle matches zero or more
 // direcotries. '/' or '/**' at the end matches any files or directories in
-// the subdirectories.
+// the subdirectories. '**' must be a whole path segment. Other '**', like
+// '**' as the whole pattern or '**' right after '**/', matches a name like
+// '*'.
 func NewMatcher(includes, excludes []string) (Matcher, error) {
-	include, err := convertGlobs(includes)
+	return NewMatcherWithOptions(includes, excludes, nil)
+}
+
+// MatcherOptions holds options for NewMatcherWithOptions.
+type MatcherOptions struct {
+	// If LiteralBrackets is true, '[' is matched literally as in the older
+	// versions which did not support bracket expressions.
+	LiteralBrackets bool
+
+	// If LiteralBraces is true, '{' and '}' are matched literally as in
+	// the older versions which did not support brace expansions.
+	LiteralBraces bool
+
+	// If CaseInsensitive is true, patterns are matched under the Unicode
+	// case folding, so "*.go" matches "Foo.GO".
+	CaseInsensitive bool
+
+	// If DirOnly is true, a pattern with '/' at the end matches only a
+	// directory and paths under it, like "build/" in .gitignore. '/**' at
+	// the end is not affected. Match treats a path with '/' at the end as
+	// a directory and other paths as files, and RecurReadDir tells the
+	// type of each entry with MatchEntry.
+	DirOnly bool
+}
+
+// splitDirSlash removes '/' at the end of path if DirOnly is true, and
+// returns whether path is a directory.
+func (opts *MatcherOptions) splitDirSlash(path string) (string, bool) {
+	if !opts.DirOnly || len(path) < 2 || !strings.HasSuffix(path, "/") {
+		return path, false
+	}
+	return path[:len(path)-len("/")], true
+}
+
+// NewMatcherWithOptions is like NewMatcher with options. opts may be nil.
+//
+// If a pattern has a syntax error, the returned error is a *PatternError.
+func NewMatcherWithOptions(includes, excludes []string, opts *MatcherOptions) (Matcher, error) {
+	return newGlobMatcher(includes, excludes, opts, newGlobSet)
+}
+
+func newGlobMatcher(includes, excludes []string, opts *MatcherOptions, newSet newPathSetFunc) (*globMatcher, error) {
+	if opts == nil {
+		opts = &MatcherOptions{}
+	}
+
+	m := &globMatcher{
+		includePatterns: includes,
+		excludePatterns: excludes,
+		opts:            opts,
+		newSet:          newSet}
+
+	var err error
+	m.includeGlobs, err = parsePatterns(includes, opts)
 	if err != nil {
 		return nil, err
 	}
-
-	exclude, err := convertGlobs(excludes)
+	m.excludeGlobs, err = parsePatterns(excludes, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return &matcherRegexp{include, exclude}, nil
+	excludeGlobs := flattenGlobs(m.excludeGlobs)
+	m.include = newEntrySet(flattenGlobs(m.includeGlobs), opts, newSet)
+	m.exclude = newEntrySet(excludeGlobs, opts, newSet)
+	m.excludeTree = newSet(treeGlobs(excludeGlobs), opts)
+	return m, nil
+}
+
+func (m *globMatcher) Match(path string) bool {
+	path, isDir := m.opts.splitDirSlash(path)
+	return m.match(path, isDir)
+}
+
+// MatchEntry is like Match, but the entry is a directory if info tells so.
+func (m *globMatcher) MatchEntry(path string, info os.FileInfo) bool {
+	return m.match(path, info.IsDir())
+}
+
+func (m *globMatcher) match(path string, isDir bool) bool {
+	return (m.include == nil || m.include.match(path, isDir)) &&
+		(m.exclude == nil || !m.exclude.match(path, isDir))
 }
 
-func (m *matcherRegexp) Match(path string) bool {
-	return (m.include == nil || m.include.MatchString(path)) &&
-		(m.exclude == nil || !m.exclude.MatchString(path))
+func (m *globMatcher) Prune(dir string) bool {
+	return m.excludeTree != nil && m.excludeTree.MatchString(dir)
+}
+
+// Explain returns the explanation of Match(path). Include is the first
+// include pattern which matched the path, and Exclude is the first exclude
+// pattern which matched the path.
+func (m *globMatcher) Explain(path string) *Explanation {
+	m.explainOnce.Do(func() {
+		m.includeSets = m.patternSets(m.includeGlobs)
+		m.excludeSets = m.patternSets(m.excludeGlobs)
+	})
+
+	e := &Explanation{Path: path, Matched: m.Match(path)}
+	path, isDir := m.opts.splitDirSlash(path)
+	e.Include = firstPatternMatch(m.includeSets, m.includePatterns, path, isDir)
+	e.Exclude = firstPatternMatch(m.excludeSets, m.excludePatterns, path, isDir)
+	return e
+}
+
+// patternSets returns a set for each of patterns.
+func (m *globMatcher) patternSets(globs [][]*glob) []*entrySet {
+	sets := make([]*entrySet, len(globs))
+	for i, g := range globs {
+		sets[i] = newEntrySet(g, m.opts, m.newSet)
+	}
+	return sets
+}
+
+func firstPatternMatch(sets []*entrySet, patterns []string, path string, isDir bool) *PatternMatch {
+	for i, set := range sets {
+		if set != nil && set.match(path, isDir) {
+			return &PatternMatch{Index: i, Pattern: patterns[i]}
+		}
+	}
+	return nil
+}
+
+// parsePatterns parses each of patterns. It returns a *PatternError for
+// the first pattern which has a syntax error.
+func parsePatterns(patterns []string, opts *MatcherOptions) ([][]*glob, error) {
+	globs := make([][]*glob, len(patterns))
+	for i, pattern := range patterns {
+		var gerr *globError
+		globs[i], gerr = parsePattern(pattern, opts)
+		if gerr != nil {
+			return nil, &PatternError{
+				Pattern: pattern,
+				Index:   i,
+				Offset:  gerr.offset,
+				Reason:  gerr.reason}
+		}
+	}
+	return globs, nil
+}
+
+func flattenGlobs(globs [][]*glob) []*glob {
+	var flat []*glob
+	for _, g := range globs {
+		flat = append(flat, g...)
+	}
+	return flat
 }
 
 var DefaultExcludes = []string{
@@ -70,45 +237,3 @@ var DefaultExcludes = []string{
 	"**/.bzr/**",
 	"**/.bzrignore",
 }
-
-func convertGlobs(patterns []string) (*regexp.Regexp, error) {
-	if len(patterns) == 0 {
-		return nil, nil
-	}
-
-	exprs := make([]string, len(patterns))
-	for i, pattern := range patterns {
-		var prefix, suffix string
-
-		if strings.HasPrefix(pattern, "**/") {
-			prefix = "(.+/)?"
-			pattern = pattern[len("**/"):]
-		}
-
-		if strings.HasSuffix(pattern, "/") {
-			suffix = "(/.+)?"
-			pattern = pattern[:len(pattern)-len("/")]
-		} else if strings.HasSuffix(pattern, "/**") {
-			suffix = "(/.+)?"
-			pattern = pattern[:len(pattern)-len("/**")]
-		}
-
-		exprs[i] = prefix + replacer.Replace(pattern) + suffix
-	}
-	return regexp.Compile(`\A(` + strings.Join(exprs, "|") + `)\z`)
-}
-
-var replacer = strings.NewReplacer(
-	"/**/", "/(.+/)?",
-	"*", "[^/]*",
-	"?", "[^/]",
-	".", `\.`,
-	"|", `\|`,
-	"+", `\+`,
-	"[", `\[`,
-	"(", `\(`,
-	"{", `\{`,
-	"^", `\^`,
-	"$", `\$`,
-	`\`, `\\`,
-)
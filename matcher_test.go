@@ -1,6 +1,7 @@
 package paths_test
 
 import (
+	"errors"
 	. "github.com/hnakamur/paths"
 	"testing"
 )
@@ -27,7 +28,11 @@ func Example() error {
 }
 
 func testMatcher(t *testing.T, includes, excludes []string, cases []testCase) {
-	matcher, err := NewMatcher(includes, excludes)
+	testMatcherWithOptions(t, includes, excludes, nil, cases)
+}
+
+func testMatcherWithOptions(t *testing.T, includes, excludes []string, opts *MatcherOptions, cases []testCase) {
+	matcher, err := NewMatcherWithOptions(includes, excludes, opts)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -164,10 +169,382 @@ func TestIncludesAndExcludes(t *testing.T) {
 func TestEscapeMeta(t *testing.T) {
 	testMatcher(t, []string{"a|b"}, nil, []testCase{{"a|b", true}})
 	testMatcher(t, []string{"a+b"}, nil, []testCase{{"a+b", true}})
-	testMatcher(t, []string{"a[b"}, nil, []testCase{{"a[b", true}})
+	testMatcherWithOptions(t, []string{"a[b"}, nil, &MatcherOptions{LiteralBrackets: true}, []testCase{{"a[b", true}})
 	testMatcher(t, []string{"a{b"}, nil, []testCase{{"a{b", true}})
 	testMatcher(t, []string{"a(b"}, nil, []testCase{{"a(b", true}})
 	testMatcher(t, []string{"a^b"}, nil, []testCase{{"a^b", true}})
 	testMatcher(t, []string{"a$b"}, nil, []testCase{{"a$b", true}})
 	testMatcher(t, []string{`a\b`}, nil, []testCase{{`a\b`, true}})
+	testMatcher(t, []string{"a)b"}, nil, []testCase{{"a)b", true}})
+	testMatcher(t, []string{"a]b"}, nil, []testCase{{"a]b", true}})
+}
+
+func TestBracket(t *testing.T) {
+	testMatcher(t,
+		[]string{
+			"*.[ch]",
+			"[!.]*.txt",
+			"log-[0-9][0-9].log",
+			"[]x]",
+			`[\]y]`,
+			"[^a-c-]",
+			"[[:upper:][:digit:]].md",
+			"a[!b]c",
+			"d[%-0]e",
+		},
+		nil,
+		[]testCase{
+			{"foo.c", true},
+			{"foo.h", true},
+			{"foo.o", false},
+			{"readme.txt", true},
+			{".hidden.txt", false},
+			{"log-01.log", true},
+			{"log-1.log", false},
+			{"log-ab.log", false},
+			{"]", true},
+			{"x", true},
+			{"y", true},
+			{"d", true},
+			{"a", false},
+			{"-", false},
+			{"A.md", true},
+			{"3.md", true},
+			{"a.md", false},
+			{"abc", false},
+			{"axc", true},
+			{"a/c", false},
+			{"d%e", true},
+			{"d0e", true},
+			{"d/e", false},
+		})
+
+	testMatcherWithOptions(t, []string{"*.[ch]"}, nil, &MatcherOptions{LiteralBrackets: true}, []testCase{
+		{"foo.[ch]", true},
+		{"foo.c", false},
+	})
+}
+
+func TestBracketError(t *testing.T) {
+	for _, pattern := range []string{"a[b", "[]", "[!]", "[z-a]", "[[:foo:]]", `[a\`} {
+		if _, err := NewMatcher([]string{pattern}, nil); err == nil {
+			t.Errorf("pattern:%s\texpected error", pattern)
+		}
+	}
+}
+
+func TestPatternError(t *testing.T) {
+	for _, c := range []struct {
+		includes []string
+		excludes []string
+		expected PatternError
+	}{
+		{[]string{"*.go", "src/a[b"}, nil, PatternError{"src/a[b", 1, 5, "unterminated bracket expression"}},
+		{[]string{"[z-a]"}, nil, PatternError{"[z-a]", 0, 1, `invalid range "z-a"`}},
+		{[]string{"x/[[:foo:]]"}, nil, PatternError{"x/[[:foo:]]", 0, 3, `unknown character class "foo"`}},
+		{[]string{"a**/b"}, nil, PatternError{"a**/b", 0, 1, "'**' must be a whole path segment"}},
+		{nil, []string{"ok", "src/***"}, PatternError{"src/***", 1, 4, "'**' must be a whole path segment"}},
+		{[]string{"{a,bb}/c**"}, nil, PatternError{"{a,bb}/c**", 0, 8, "'**' must be a whole path segment"}},
+	} {
+		_, err := NewMatcher(c.includes, c.excludes)
+		var perr *PatternError
+		if !errors.As(err, &perr) {
+			t.Errorf("includes=%q, excludes=%q: err=%v, expected *PatternError", c.includes, c.excludes, err)
+			continue
+		}
+		if *perr != c.expected {
+			t.Errorf("err=%+v, expected=%+v", *perr, c.expected)
+		}
+	}
+
+	_, err := NewRuleMatcher([]string{"build/**", "!build/[a"}, nil)
+	var perr *PatternError
+	if !errors.As(err, &perr) || perr.Index != 1 || perr.Offset != 7 {
+		t.Errorf("err=%v, expected *PatternError at offset 7 in pattern #1", err)
+	}
+}
+
+func TestDoubleStarAtEnd(t *testing.T) {
+	// '**' at the end other than '/**' matches a name like '*'
+	testMatcher(t, []string{"**"}, nil, []testCase{
+		{"", true},
+		{"a", true},
+		{"a/b", false},
+		{"a/b/c", false},
+	})
+	testMatcher(t, []string{"**/**"}, nil, []testCase{
+		{"", true},
+		{"a", true},
+		{"a/b", true},
+	})
+	testMatcher(t, []string{"a/**/**"}, nil, []testCase{
+		{"a", false},
+		{"a/", true},
+		{"a/b", true},
+		{"a/b/c", true},
+	})
+}
+
+func TestConsecutiveDoubleStars(t *testing.T) {
+	// '**' right after '**/' matches a name like '*'
+	testMatcher(t, []string{"a/**/**/b"}, nil, []testCase{
+		{"a/b", false},
+		{"a//b", true},
+		{"a/x/b", true},
+		{"a/x/y/b", true},
+	})
+	testMatcher(t, []string{"**/**/a"}, nil, []testCase{
+		{"a", false},
+		{"/a", true},
+		{"x/a", true},
+		{"x/y/a", true},
+	})
+	testMatcher(t, []string{"a/**/**/**/b"}, nil, []testCase{
+		{"a/b", false},
+		{"a/x/b", true},
+		{"a/x/y/b", true},
+	})
+}
+
+func TestPrune(t *testing.T) {
+	matcher, err := NewMatcher([]string{"src/"}, append(DefaultExcludes, "**/*.o", "build/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pruner, ok := matcher.(Pruner)
+	if !ok {
+		t.Fatal("matcher is not a Pruner")
+	}
+
+	cases := []testCase{
+		{"src", false},
+		{"src/foo", false},
+		{"src/.git", true},
+		{"src/foo/.svn", true},
+		{"src/.gitignore", false},
+		{"src/foo.o", false},
+		{"build", true},
+		{"src/build", false},
+	}
+	for _, c := range cases {
+		actual := pruner.Prune(c.path)
+		if actual != c.matches {
+			t.Errorf("path:%s\texpected:%v\tactual:%v", c.path, c.matches, actual)
+		}
+	}
+}
+
+func TestBraces(t *testing.T) {
+	testMatcher(t,
+		[]string{
+			"**/*.{go,mod,sum}",
+			"{src,docs}/",
+			"file{1..12}.txt",
+			"log{08..10}",
+			"a{b,c{d,e}f}g",
+			"x{}y",
+			"{single}",
+			"n{-1..1}",
+			"[{]p,q}",
+		},
+		nil,
+		[]testCase{
+			{"main.go", true},
+			{"foo/go.mod", true},
+			{"foo/bar/go.sum", true},
+			{"foo.md", false},
+			{"src", true},
+			{"src/main.c", true},
+			{"docs/index.md", true},
+			{"foo/src/main.c", false},
+			{"file1.txt", true},
+			{"file12.txt", true},
+			{"file13.txt", false},
+			{"file01.txt", false},
+			{"log08", true},
+			{"log09", true},
+			{"log10", true},
+			{"log8", false},
+			{"abg", true},
+			{"acdfg", true},
+			{"acefg", true},
+			{"acg", false},
+			{"x{}y", true},
+			{"{single}", true},
+			{"single", false},
+			{"n-1", true},
+			{"n0", true},
+			{"n1", true},
+			{"{p,q}", true},
+			{"p", false},
+		})
+
+	testMatcherWithOptions(t, []string{"*.{go,mod}"}, nil, &MatcherOptions{LiteralBraces: true}, []testCase{
+		{"a.{go,mod}", true},
+		{"a.go", false},
+	})
+}
+
+func TestBracesPrune(t *testing.T) {
+	matcher, err := NewMatcher(nil, []string{"{build,dist}/**", "**/{.git,node_modules}/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pruner := matcher.(Pruner)
+	for _, c := range []testCase{
+		{"build", true},
+		{"dist", true},
+		{"src", false},
+		{"src/.git", true},
+		{"src/node_modules", true},
+	} {
+		actual := pruner.Prune(c.path)
+		if actual != c.matches {
+			t.Errorf("path:%s\texpected:%v\tactual:%v", c.path, c.matches, actual)
+		}
+	}
+}
+
+func TestTooManyBraces(t *testing.T) {
+	pattern := "{a,b}{a,b}{a,b}{a,b}{a,b}{a,b}{a,b}{a,b}{a,b}{a,b}{a,b}{a,b}{a,b}{a,b}"
+	if _, err := NewMatcher([]string{pattern}, nil); err == nil {
+		t.Errorf("pattern:%s\texpected error", pattern)
+	}
+}
+
+func TestHugeNumericRange(t *testing.T) {
+	for _, pattern := range []string{
+		"{-9223372036854775808..9223372036854775807}",
+		"{9223372036854775807..-9223372036854775808}",
+		"{1..000000000000000000000000000000000000000000000000000000000000002}",
+	} {
+		testMatcher(t, []string{pattern}, nil, []testCase{
+			{pattern, true},
+			{"1", false},
+		})
+	}
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	opts := &MatcherOptions{CaseInsensitive: true}
+	testMatcherWithOptions(t,
+		[]string{"**/*.go", "README.md", "docs/", "σίσυφος.*", "[a-c]*.txt"},
+		[]string{"**/vendor/**"},
+		opts,
+		[]testCase{
+			{"Foo.GO", true},
+			{"src/Bar.Go", true},
+			{"readme.MD", true},
+			{"DOCS/index.html", true},
+			{"ΣΊΣΥΦΟΣ.txt", true},
+			{"Apple.txt", true},
+			{"Dog.txt", false},
+			{"Vendor/foo.go", false},
+			{"a/VENDOR/foo.go", false},
+		})
+
+	testMatcher(t, []string{"**/*.go", "README.md"}, nil, []testCase{
+		{"foo.go", true},
+		{"Foo.GO", false},
+		{"readme.MD", false},
+	})
+
+	matcher, err := NewMatcherWithOptions(nil, []string{"**/.GIT/"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !matcher.(Pruner).Prune("src/.git") {
+		t.Errorf("Prune(src/.git)=false, expected=true")
+	}
+}
+
+func TestDirOnly(t *testing.T) {
+	opts := &MatcherOptions{DirOnly: true}
+	testMatcherWithOptions(t, nil, []string{"**/logs/", "tmp/**"}, opts, []testCase{
+		{"logs", true},
+		{"logs/", false},
+		{"a/logs/", false},
+		{"a/logs/b", false},
+		{"a/logs/b/", false},
+		{"a/logsx/", true},
+		{"tmp", false},
+		{"tmp/", false},
+		{"tmp/a", false},
+	})
+	testMatcherWithOptions(t, []string{"src/"}, nil, opts, []testCase{
+		{"src", false},
+		{"src/", true},
+		{"src/a.go", true},
+	})
+
+	matcher, err := NewRuleMatcher([]string{"build/", "!build/keep/"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []testCase{
+		{"build", true},
+		{"build/", false},
+		{"build/a", false},
+		{"build/keep", false},
+		{"build/keep/", true},
+		{"build/keep/a", true},
+	} {
+		if actual := matcher.Match(c.path); actual != c.matches {
+			t.Errorf("path:%s\texpected:%v\tactual:%v", c.path, c.matches, actual)
+		}
+	}
+}
+
+func checkExplanation(t *testing.T, e *Explanation, matched bool, include, exclude *PatternMatch) {
+	if e.Matched != matched {
+		t.Errorf("path:%s\tMatched=%v, expected=%v", e.Path, e.Matched, matched)
+	}
+	checkPatternMatch(t, e.Path, "Include", e.Include, include)
+	checkPatternMatch(t, e.Path, "Exclude", e.Exclude, exclude)
+}
+
+func checkPatternMatch(t *testing.T, path, name string, actual, expected *PatternMatch) {
+	if actual == nil || expected == nil {
+		if actual != expected {
+			t.Errorf("path:%s\t%s=%v, expected=%v", path, name, actual, expected)
+		}
+		return
+	}
+	if *actual != *expected {
+		t.Errorf("path:%s\t%s=%v, expected=%v", path, name, *actual, *expected)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	matcher, err := NewMatcher(
+		[]string{"*.md", "src/", "**/*.{c,h}"},
+		append(DefaultExcludes, "**/*.o", "tmp/**/*.c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	explainer := matcher.(Explainer)
+	n := len(DefaultExcludes)
+
+	checkExplanation(t, explainer.Explain("README.md"), true,
+		&PatternMatch{Index: 0, Pattern: "*.md"}, nil)
+	checkExplanation(t, explainer.Explain("src/foo.c"), true,
+		&PatternMatch{Index: 1, Pattern: "src/"}, nil)
+	checkExplanation(t, explainer.Explain("lib/foo.h"), true,
+		&PatternMatch{Index: 2, Pattern: "**/*.{c,h}"}, nil)
+	checkExplanation(t, explainer.Explain("src/foo.o"), false,
+		&PatternMatch{Index: 1, Pattern: "src/"},
+		&PatternMatch{Index: n, Pattern: "**/*.o"})
+	checkExplanation(t, explainer.Explain("src/.git/config"), false,
+		&PatternMatch{Index: 1, Pattern: "src/"},
+		&PatternMatch{Index: 11, Pattern: "**/.git/**"})
+	checkExplanation(t, explainer.Explain("tmp/foo.c"), false,
+		&PatternMatch{Index: 2, Pattern: "**/*.{c,h}"},
+		&PatternMatch{Index: n + 1, Pattern: "tmp/**/*.c"})
+	checkExplanation(t, explainer.Explain("lib/foo.go"), false, nil, nil)
+
+	matcher, err = NewMatcher(nil, []string{"**/*.o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkExplanation(t, matcher.(Explainer).Explain("foo.c"), true, nil, nil)
 }
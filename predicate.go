@@ -0,0 +1,88 @@
+package paths
+
+import (
+	"os"
+	"time"
+)
+
+// An EntryMatcher is a Matcher which can also match entries by their
+// attributes. RecurReadDir calls MatchEntry instead of Match for each entry.
+type EntryMatcher interface {
+	Matcher
+
+	// MatchEntry returns true if the entry at path with info matches.
+	MatchEntry(path string, info os.FileInfo) bool
+}
+
+// infoMatcher is an EntryMatcher which matches entries by info only.
+// Match returns true for any path since the path alone does not tell the
+// attributes.
+type infoMatcher func(info os.FileInfo) bool
+
+func (f infoMatcher) Match(path string) bool {
+	return true
+}
+
+func (f infoMatcher) MatchEntry(path string, info os.FileInfo) bool {
+	return f(info)
+}
+
+// SizeRange returns an EntryMatcher which matches entries whose sizes are
+// between min and max inclusive. A negative max means no upper limit.
+//
+// For example, the following matches '*.log' files of 10MB or larger:
+//
+//	MatchAll(logs, SizeRange(10<<20, -1))
+func SizeRange(min, max int64) EntryMatcher {
+	return infoMatcher(func(info os.FileInfo) bool {
+		size := info.Size()
+		return size >= min && (max < 0 || size <= max)
+	})
+}
+
+// ModTimeRange returns an EntryMatcher which matches entries modified at
+// or after from and before to. A zero from or to means no limit.
+//
+// For example, the following matches entries older than 7 days:
+//
+//	ModTimeRange(time.Time{}, time.Now().AddDate(0, 0, -7))
+func ModTimeRange(from, to time.Time) EntryMatcher {
+	return infoMatcher(func(info os.FileInfo) bool {
+		t := info.ModTime()
+		return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+	})
+}
+
+// FileType returns an EntryMatcher which matches entries whose types are
+// one of types, like os.ModeDir or os.ModeSymlink. Use 0 for regular
+// files.
+func FileType(types ...os.FileMode) EntryMatcher {
+	return infoMatcher(func(info os.FileInfo) bool {
+		typ := info.Mode().Type()
+		for _, t := range types {
+			if typ == t {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ModeBits returns an EntryMatcher which matches entries whose mode bits
+// masked by mask are bits. For example, ModeBits(0111, 0111) matches
+// entries executable by everyone, and ModeBits(0002, 0) matches entries
+// not writable by others.
+func ModeBits(mask, bits os.FileMode) EntryMatcher {
+	return infoMatcher(func(info os.FileInfo) bool {
+		return info.Mode()&mask == bits
+	})
+}
+
+// matchEntry returns m.MatchEntry(path, info) if m is an EntryMatcher, or
+// m.Match(path) otherwise.
+func matchEntry(m Matcher, path string, info os.FileInfo) bool {
+	if em, ok := m.(EntryMatcher); ok {
+		return em.MatchEntry(path, info)
+	}
+	return m.Match(path)
+}
@@ -0,0 +1,58 @@
+package paths_test
+
+import (
+	. "github.com/hnakamur/paths"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestEntryMatchers(t *testing.T) {
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	old := now.AddDate(0, 0, -10)
+	fsys := fstest.MapFS{
+		"logs/big-old.log":   {Data: make([]byte, 2000), ModTime: old, Mode: 0644},
+		"logs/big-new.log":   {Data: make([]byte, 2000), ModTime: now, Mode: 0644},
+		"logs/small-old.log": {Data: make([]byte, 10), ModTime: old, Mode: 0644},
+		"logs/old.txt":       {Data: make([]byte, 2000), ModTime: old, Mode: 0644},
+		"logs/run.sh":        {ModTime: old, Mode: 0755},
+		"logs/sub":           {ModTime: old, Mode: os.ModeDir | 0755},
+		"logs/sub/a.log":     {Data: make([]byte, 2000), ModTime: old, Mode: 0600},
+	}
+	logs, err := NewMatcher([]string{"**/*.log"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weekAgo := now.AddDate(0, 0, -7)
+
+	for _, c := range []struct {
+		matcher  Matcher
+		expected []string
+	}{
+		{MatchAll(logs, ModTimeRange(time.Time{}, weekAgo), SizeRange(1000, -1)),
+			[]string{"logs/big-old.log", "logs/sub/a.log"}},
+		{SizeRange(1, 100), []string{"logs/small-old.log"}},
+		{ModTimeRange(weekAgo, time.Time{}), []string{"logs/big-new.log"}},
+		{FileType(os.ModeDir), []string{"logs/sub"}},
+		{MatchAll(FileType(0), ModeBits(0111, 0111)), []string{"logs/run.sh"}},
+		{MatchAll(logs, Not(ModeBits(0044, 0044))), []string{"logs/sub/a.log"}},
+		{MatchAny(FileType(os.ModeDir), SizeRange(0, 0)), []string{"logs/run.sh", "logs/sub"}},
+	} {
+		var names []string
+		for fi, err := range All("logs", &ReadDirOptions{FS: fsys, Matcher: c.matcher}) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, fi.Name())
+		}
+		if strings.Join(names, " ") != strings.Join(c.expected, " ") {
+			t.Errorf("names=%v, expected=%v", names, c.expected)
+		}
+	}
+
+	if !SizeRange(1, 2).Match("any") {
+		t.Errorf("Match(any)=false, expected=true")
+	}
+}
@@ -2,139 +2,125 @@
 package paths
 
 import (
+	"context"
+	"errors"
+	"io/fs"
 	"io/ioutil"
 	"os"
-	"path"
-	"time"
+)
+
+var (
+	// ErrInvalidMarker is returned when a marker is not a clean path or
+	// is same as the directory to read.
+	ErrInvalidMarker = errors.New("paths: invalid marker")
+
+	// ErrMarkerOutsideRoot is returned when a marker is not under the
+	// directory to read.
+	ErrMarkerOutsideRoot = errors.New("paths: marker is outside of dir")
 )
 
 // Read directory entries recursively with depth-first order.
 // Entries in each directory are sorted by names.
 // Entries in a directory follows the directory.
-// Name() for an entry returns a path starting with dir.
+// Name() for an entry returns a path starting with dir. The entry also has
+// the methods of *Entry other than Name, like RelPath and Depth.
 // If matcher is specified, only entries which matches will be returned.
+// If matcher is also a Pruner, directories pruned by it will not be read.
+// If matcher is an EntryMatcher, entries are matched with MatchEntry.
 // If marker is specified, entries after maker will be returned.
+// marker is compared by the sort order, so it need not exist.
+// marker must be a clean path under dir, otherwise an error which wraps
+// ErrInvalidMarker or ErrMarkerOutsideRoot is returned.
 // If maxEntries is greater than zero, the number of entries will be limited.
 func RecurReadDir(dir string, matcher Matcher, marker string, maxEntries int) (entries []os.FileInfo, err error) {
-	r := &recurDirReader{dir, matcher, marker, maxEntries, ioutil.ReadDir}
-	return r.recurReadDir()
-}
-
-type recurDirReader struct {
-	dir         string
-	matcher     Matcher
-	marker      string
-	maxEntries  int
-	readDirFunc func(string) ([]os.FileInfo, error)
+	return RecurReadDirContext(context.Background(), dir, matcher, marker, maxEntries)
 }
 
-func (r *recurDirReader) recurReadDir() ([]os.FileInfo, error) {
-	entries := make([]os.FileInfo, 0)
-	if r.marker != "" {
-		return r.appendReadDirAfterMarker(r.marker, entries)
-	} else {
-		return r.appendReadDir(r.dir, entries)
-	}
+// RecurReadDirContext is like RecurReadDir but stops reading when ctx is
+// done. ctx is checked before reading each directory and each entry.
+// When ctx is done, it returns entries read so far and ctx.Err().
+func RecurReadDirContext(ctx context.Context, dir string, matcher Matcher, marker string, maxEntries int) (entries []os.FileInfo, err error) {
+	r := &recurDirReader{
+		dir:         dir,
+		matcher:     matcher,
+		marker:      marker,
+		maxEntries:  maxEntries,
+		readDirFunc: ioutil.ReadDir,
+		ctx:         ctx}
+	return r.recurReadDir()
 }
 
-func (r *recurDirReader) appendReadDir(dirname string, entries []os.FileInfo) ([]os.FileInfo, error) {
-	infos, err := r.readDirFunc(dirname)
-	if err != nil {
-		return entries, err
-	}
-
-	return r.processFileInfos(dirname, infos, entries)
+// RecurReadDirFS is like RecurReadDir but reads directories from fsys.
+// dir and marker are slash-separated paths in fsys, and dir is "." for
+// the root of fsys.
+func RecurReadDirFS(fsys fs.FS, dir string, matcher Matcher, marker string, maxEntries int) (entries []os.FileInfo, err error) {
+	r := &recurDirReader{
+		dir:         dir,
+		matcher:     matcher,
+		marker:      marker,
+		maxEntries:  maxEntries,
+		readDirFunc: fsReadDirFunc(fsys)}
+	return r.recurReadDir()
 }
 
-func (r *recurDirReader) appendReadDirAfterMarker(marker string, entries []os.FileInfo) ([]os.FileInfo, error) {
-	markerBase := path.Base(marker)
-	markerDir := path.Dir(marker)
-	infos, err := r.readDirFunc(markerDir)
-	if err != nil {
-		return entries, err
-	}
-
-	i := indexOfName(infos, markerBase)
-
-	if marker == r.marker && infos[i].IsDir() {
-		subinfos, err := r.readDirFunc(marker)
+// fsReadDirFunc returns a function which reads a directory in fsys and
+// returns entries sorted by names like ioutil.ReadDir.
+func fsReadDirFunc(fsys fs.FS) func(string) ([]os.FileInfo, error) {
+	return func(dirname string) ([]os.FileInfo, error) {
+		ents, err := fs.ReadDir(fsys, dirname)
 		if err != nil {
-			return entries, err
+			return nil, err
 		}
-		entries, err = r.processFileInfos(marker, subinfos, entries)
-		if err != nil || r.hasReachedLimit(entries) {
-			return entries, err
-		}
-	}
-
-	entries, err = r.processFileInfos(markerDir, infos[i+1:], entries)
-	if err != nil || r.hasReachedLimit(entries) {
-		return entries, err
-	}
 
-	for ; markerDir != r.dir; markerDir = path.Dir(markerDir) {
-		entries, err = r.appendReadDirAfterMarker(markerDir, entries)
-		if err != nil || r.hasReachedLimit(entries) {
-			return entries, err
+		infos := make([]os.FileInfo, 0, len(ents))
+		for _, ent := range ents {
+			info, err := ent.Info()
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					// removed after reading the directory
+					continue
+				}
+				return nil, err
+			}
+			infos = append(infos, info)
 		}
+		return infos, nil
 	}
-	return entries, nil
 }
 
-func indexOfName(infos []os.FileInfo, name string) int {
-	i := 0
-	for ; i < len(infos); i++ {
-		if infos[i].Name() == name {
-			break
-		}
-	}
-	return i
+type recurDirReader struct {
+	dir         string
+	matcher     Matcher
+	marker      string
+	maxEntries  int
+	readDirFunc func(string) ([]os.FileInfo, error)
+	ctx         context.Context // nil means context.Background()
+
+	continueOnError bool
+
+	readFileFunc     func(string) ([]byte, error)
+	ignoreFile       string
+	foldCase         bool
+	matchRelative    bool
+	symlinks         SymlinkPolicy
+	statFunc         func(string) (os.FileInfo, error)
+	evalSymlinksFunc func(string) (string, error)
+	absFunc          func(string) (string, error) // nil for fs.FS
 }
 
-func (r *recurDirReader) processFileInfos(dirname string, infos []os.FileInfo,
-	entries []os.FileInfo) ([]os.FileInfo, error) {
-	var err error
-	for _, info := range infos {
-		entryPath := path.Join(dirname, info.Name())
-		if r.matcher == nil || r.matcher.Match(entryPath) {
-			entry := &dirEntry{
-				entryPath,
-				info.Size(),
-				info.Mode(),
-				info.ModTime(),
-				info.Sys()}
-			entries = append(entries, entry)
-			if r.hasReachedLimit(entries) {
-				return entries, nil
-			}
-		}
-
-		if info.IsDir() {
-			subdir := path.Join(dirname, info.Name())
-			entries, err = r.appendReadDir(subdir, entries)
-			if err != nil || r.hasReachedLimit(entries) {
-				return entries, err
-			}
-		}
+func (r *recurDirReader) recurReadDir() ([]os.FileInfo, error) {
+	entries := make([]os.FileInfo, 0)
+	w := r.newWalker()
+	for w.Next() {
+		entries = append(entries, w.Entry())
 	}
-	return entries, nil
-}
-
-func (r *recurDirReader) hasReachedLimit(entries []os.FileInfo) bool {
-	return r.maxEntries > 0 && len(entries) >= r.maxEntries
+	return entries, w.Err()
 }
 
+// dirEntry is an Entry whose Name returns the path starting with the
+// directory to read, for compatibility with the older versions.
 type dirEntry struct {
-	name    string      // the file name with the relative direcotry
-	size    int64       // length in bytes for regular files; system-dependent for others
-	mode    os.FileMode // file mode bits
-	modTime time.Time   // modification time
-	sys     interface{} // underlying data source (can return nil)
+	*Entry
 }
 
-func (e *dirEntry) Name() string       { return e.name }
-func (e *dirEntry) Size() int64        { return e.size }
-func (e *dirEntry) Mode() os.FileMode  { return e.mode }
-func (e *dirEntry) ModTime() time.Time { return e.modTime }
-func (e *dirEntry) IsDir() bool        { return e.Mode().IsDir() }
-func (e *dirEntry) Sys() interface{}   { return e.sys }
+func (e dirEntry) Name() string { return e.path }
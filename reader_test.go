@@ -1,9 +1,13 @@
 package paths
 
 import (
+	"context"
+	"errors"
 	"os"
+	"path"
 	"sort"
 	"testing"
+	"testing/fstest"
 	"time"
 )
 
@@ -371,3 +375,244 @@ func TestRecurReadDirMarkerLimitMatcher(t *testing.T) {
 		&resultFileInfo{false, "archive/zip/struct.go"},
 	})
 }
+
+func TestRecurReadDirPrune(t *testing.T) {
+	matcher, err := NewMatcher(nil, []string{"**/testdata/**"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	var readDirs []string
+	readDir := fakeReaderDirFunc(newFakeFS())
+	r := recurDirReader{
+		dir: "archive", matcher: matcher,
+		readDirFunc: func(dirname string) ([]os.FileInfo, error) {
+			readDirs = append(readDirs, dirname)
+			return readDir(dirname)
+		}}
+	fis, err := r.recurReadDir()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	if len(fis) != 15 {
+		t.Errorf("len(fis)=%d, expected=15", len(fis))
+	}
+	for _, dirname := range readDirs {
+		if path.Base(dirname) == "testdata" {
+			t.Errorf("%s should not be read", dirname)
+		}
+	}
+}
+
+func newFakeMapFS() fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for dirname, fi := range newFakeFS() {
+		for _, ent := range fi.ents {
+			if !ent.dir {
+				fsys[path.Join(dirname, ent.basename)] = &fstest.MapFile{}
+			}
+		}
+	}
+	return fsys
+}
+
+func TestRecurReadDirFS(t *testing.T) {
+	fsys := newFakeMapFS()
+	for _, c := range []struct {
+		marker     string
+		maxEntries int
+	}{
+		{"", 0},
+		{"", 7},
+		{"archive/tar/reader.go", 0},
+		{"archive/tar/testdata", 7},
+	} {
+		r := recurDirReader{
+			dir: "archive", marker: c.marker, maxEntries: c.maxEntries,
+			readDirFunc: fakeReaderDirFunc(newFakeFS())}
+		expected, err := r.recurReadDir()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+
+		fis, err := RecurReadDirFS(fsys, "archive", nil, c.marker, c.maxEntries)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		checkFileInfos(t, fis, expected)
+	}
+}
+
+func TestRecurReadDirFSRoot(t *testing.T) {
+	fis, err := RecurReadDirFS(newFakeMapFS(), ".", nil, "", 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	checkFileInfos(t, fis, []os.FileInfo{
+		&resultFileInfo{true, "archive"},
+		&resultFileInfo{true, "archive/tar"},
+		&resultFileInfo{false, "archive/tar/common.go"},
+	})
+}
+
+func TestRecurReadDirContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	readDir := fakeReaderDirFunc(newFakeFS())
+	r := recurDirReader{
+		dir: "archive", ctx: ctx,
+		readDirFunc: func(dirname string) ([]os.FileInfo, error) {
+			if dirname == "archive/tar/testdata" {
+				cancel()
+			}
+			return readDir(dirname)
+		}}
+	fis, err := r.recurReadDir()
+	if err != context.Canceled {
+		t.Fatalf("err=%v, expected=%v", err, context.Canceled)
+	}
+
+	checkFileInfos(t, fis, []os.FileInfo{
+		&resultFileInfo{true, "archive/tar"},
+		&resultFileInfo{false, "archive/tar/common.go"},
+		&resultFileInfo{false, "archive/tar/example_test.go"},
+		&resultFileInfo{false, "archive/tar/reader.go"},
+		&resultFileInfo{false, "archive/tar/reader_test.go"},
+		&resultFileInfo{true, "archive/tar/testdata"},
+	})
+}
+
+func TestRecurReadDirRemovedMarker(t *testing.T) {
+	fs := newFakeFS()
+	tar := fs["archive/tar"]
+	tar.ents = append(tar.ents[:2], tar.ents[3:]...) // reader.go
+	delete(fs, "archive/tar/testdata")
+	tar.ents = append(tar.ents[:3], tar.ents[4:]...) // testdata
+	readDir := fakeReaderDirFunc(fs)
+	readDirFunc := func(dirname string) ([]os.FileInfo, error) {
+		if _, ok := fs[dirname]; !ok {
+			return nil, &os.PathError{Op: "open", Path: dirname, Err: os.ErrNotExist}
+		}
+		return readDir(dirname)
+	}
+
+	for _, c := range []struct {
+		marker   string
+		expected []os.FileInfo
+	}{
+		{"archive/tar/reader.go", []os.FileInfo{
+			&resultFileInfo{false, "archive/tar/reader_test.go"},
+			&resultFileInfo{false, "archive/tar/writer.go"},
+		}},
+		{"archive/tar/testdata", []os.FileInfo{
+			&resultFileInfo{false, "archive/tar/writer.go"},
+			&resultFileInfo{false, "archive/tar/writer_test.go"},
+		}},
+		{"archive/tar/testdata/gnu.tar", []os.FileInfo{
+			&resultFileInfo{false, "archive/tar/writer.go"},
+			&resultFileInfo{false, "archive/tar/writer_test.go"},
+		}},
+		{"archive/tar/zzz", []os.FileInfo{
+			&resultFileInfo{true, "archive/zip"},
+			&resultFileInfo{false, "archive/zip/example_test.go"},
+		}},
+		{"archive/aaa", []os.FileInfo{
+			&resultFileInfo{true, "archive/tar"},
+			&resultFileInfo{false, "archive/tar/common.go"},
+		}},
+	} {
+		r := recurDirReader{
+			dir: "archive", marker: c.marker, maxEntries: 2,
+			readDirFunc: readDirFunc}
+		fis, err := r.recurReadDir()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		checkFileInfos(t, fis, c.expected)
+	}
+
+	// the root is not skipped even if it does not exist
+	r := recurDirReader{
+		dir: "nosuch", marker: "nosuch/x", maxEntries: 2,
+		readDirFunc: readDirFunc}
+	if _, err := r.recurReadDir(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("err=%v, expected=%v", err, os.ErrNotExist)
+	}
+
+	// a parent of the marker is replaced by a file
+	fs = newFakeFS()
+	fs["archive"].ents[0] = &fakeFileInfo{false, "tar", nil}
+	delete(fs, "archive/tar")
+	readDir = fakeReaderDirFunc(fs)
+	r = recurDirReader{
+		dir: "archive", marker: "archive/tar/reader.go", maxEntries: 2,
+		readDirFunc: func(dirname string) ([]os.FileInfo, error) {
+			if _, ok := fs[dirname]; !ok {
+				return nil, &os.PathError{Op: "readdirent", Path: dirname, Err: errors.New("not a directory")}
+			}
+			return readDir(dirname)
+		},
+		statFunc: func(name string) (os.FileInfo, error) {
+			for _, ent := range fs[path.Dir(name)].ents {
+				if ent.basename == path.Base(name) {
+					return ent, nil
+				}
+			}
+			return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+		}}
+	fis, err := r.recurReadDir()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	checkFileInfos(t, fis, []os.FileInfo{
+		&resultFileInfo{true, "archive/zip"},
+		&resultFileInfo{false, "archive/zip/example_test.go"},
+	})
+}
+
+func TestRecurReadDirInvalidMarker(t *testing.T) {
+	for _, c := range []struct {
+		dir      string
+		marker   string
+		expected error
+	}{
+		{"archive", "archive", ErrInvalidMarker},
+		{"archive", "archive/tar/", ErrInvalidMarker},
+		{"archive", "archive/./tar", ErrInvalidMarker},
+		{"archive", "archive/../archive/tar", ErrInvalidMarker},
+		{"archive", "tar/reader.go", ErrMarkerOutsideRoot},
+		{"archive", "archive2/tar", ErrMarkerOutsideRoot},
+		{"archive/tar", "archive/zip/reader.go", ErrMarkerOutsideRoot},
+		{"archive", "/archive/tar", ErrMarkerOutsideRoot},
+		{".", "../archive", ErrMarkerOutsideRoot},
+		{".", "/archive", ErrMarkerOutsideRoot},
+		{"/", "archive", ErrMarkerOutsideRoot},
+	} {
+		r := recurDirReader{
+			dir: c.dir, marker: c.marker,
+			readDirFunc: fakeReaderDirFunc(newFakeFS())}
+		fis, err := r.recurReadDir()
+		if !errors.Is(err, c.expected) {
+			t.Errorf("dir=%s, marker=%s: err=%v, expected=%v", c.dir, c.marker, err, c.expected)
+		}
+		if len(fis) != 0 {
+			t.Errorf("dir=%s, marker=%s: len(fis)=%d, expected=0", c.dir, c.marker, len(fis))
+		}
+	}
+}
+
+func TestRecurReadDirUncleanDir(t *testing.T) {
+	r := recurDirReader{
+		dir: "./archive/", marker: "archive/zip/writer.go",
+		readDirFunc: fakeReaderDirFunc(newFakeFS())}
+	fis, err := r.recurReadDir()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	checkFileInfos(t, fis, []os.FileInfo{
+		&resultFileInfo{false, "archive/zip/writer_test.go"},
+		&resultFileInfo{false, "archive/zip/zip_test.go"},
+	})
+}
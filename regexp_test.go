@@ -0,0 +1,154 @@
+package paths
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// regexpSet returns a pathSet which is a regular expression for globs.
+// It is the older implementation kept for comparisons.
+func regexpSet(globs []*glob, opts *MatcherOptions) pathSet {
+	if len(globs) == 0 {
+		return nil
+	}
+	return globsRegexp(globs, opts)
+}
+
+// writeRegexp writes a regular expression for the glob.
+func (g *glob) writeRegexp(b *strings.Builder) {
+	if g.leadingDirs {
+		b.WriteString("(.+/)?")
+	}
+	for i, seg := range g.segs {
+		last := i == len(g.segs)-1
+		if seg.anyDirs {
+			if last {
+				b.WriteString(".+")
+			} else {
+				b.WriteString("(.+/)?")
+			}
+			continue
+		}
+		for _, e := range seg.elems {
+			switch e.op {
+			case globLiteral:
+				b.WriteString(regexp.QuoteMeta(e.lit))
+			case globStar:
+				b.WriteString("[^/]*")
+			case globAnyChar:
+				b.WriteString("[^/]")
+			case globClass:
+				b.WriteString(e.class.regexp())
+			}
+		}
+		if !last {
+			b.WriteString("/")
+		}
+	}
+	if g.tree {
+		b.WriteString("(/.+)?")
+	}
+}
+
+// globsRegexp returns a regular expression which matches paths matching
+// one of globs, or nil if globs is empty.
+func globsRegexp(globs []*glob, opts *MatcherOptions) *regexp.Regexp {
+	if len(globs) == 0 {
+		return nil
+	}
+
+	// '.' matches '\n' as '*' does
+	var b strings.Builder
+	if opts.CaseInsensitive {
+		b.WriteString("(?si)")
+	} else {
+		b.WriteString("(?s)")
+	}
+	b.WriteString(`\A(`)
+	for i, g := range globs {
+		if i > 0 {
+			b.WriteString("|")
+		}
+		g.writeRegexp(&b)
+	}
+	b.WriteString(`)\z`)
+	return regexp.MustCompile(b.String())
+}
+
+// regexp returns a regular expression for the class.
+func (c *charClass) regexp() string {
+	var b strings.Builder
+	b.WriteString("[")
+	if c.negate {
+		b.WriteString(`^/`)
+	}
+	n := 0
+	for _, r := range c.ranges {
+		if !c.negate && r.lo <= '/' && '/' <= r.hi {
+			// exclude '/' from the range
+			if r.lo < '/' {
+				writeRange(&b, runeRange{r.lo, '/' - 1})
+				n++
+			}
+			if '/' < r.hi {
+				writeRange(&b, runeRange{'/' + 1, r.hi})
+				n++
+			}
+			continue
+		}
+		writeRange(&b, r)
+		n++
+	}
+	if n == 0 && !c.negate {
+		// matches nothing
+		return `[^\x00-\x{10FFFF}]`
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+func writeRange(b *strings.Builder, r runeRange) {
+	fmt.Fprintf(b, `\x{%x}`, r.lo)
+	if r.lo != r.hi {
+		fmt.Fprintf(b, `-\x{%x}`, r.hi)
+	}
+}
+
+// olderRegexp returns a regular expression for patterns converted as in the
+// older versions, which replaced '**/' at the beginning, '/' or '/**' at
+// the end and '/**/' in the middle with regular expressions.
+func olderRegexp(patterns []string) *regexp.Regexp {
+	exprs := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		var prefix, suffix string
+		if strings.HasPrefix(pattern, "**/") {
+			prefix = "(.+/)?"
+			pattern = pattern[len("**/"):]
+		}
+		if strings.HasSuffix(pattern, "/") {
+			suffix = "(/.+)?"
+			pattern = pattern[:len(pattern)-len("/")]
+		} else if strings.HasSuffix(pattern, "/**") {
+			suffix = "(/.+)?"
+			pattern = pattern[:len(pattern)-len("/**")]
+		}
+		exprs[i] = prefix + olderReplacer.Replace(pattern) + suffix
+	}
+	return regexp.MustCompile(`\A(` + strings.Join(exprs, "|") + `)\z`)
+}
+
+var olderReplacer = strings.NewReplacer(
+	"/**/", "/(.+/)?",
+	"*", "[^/]*",
+	"?", "[^/]",
+	".", `\.`,
+	"|", `\|`,
+	"+", `\+`,
+	"[", `\[`,
+	"(", `\(`,
+	"{", `\{`,
+	"^", `\^`,
+	"$", `\$`,
+	`\`, `\\`,
+)
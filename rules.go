@@ -0,0 +1,158 @@
+package paths
+
+import (
+	"os"
+	"strings"
+)
+
+type ruleMatcher struct {
+	rules []*rule
+	opts  *MatcherOptions
+}
+
+// rule is a compiled rule of a ruleMatcher.
+type rule struct {
+	source  string  // the rule as given
+	index   int     // the index of the rule
+	line    int     // the line number in a .gitignore file
+	pattern string  // the rule without the leading '!'
+	negate  bool    // whether the rule includes paths
+	dirOnly bool    // whether the rule matches only directories
+	globs   []*glob // globs expanded from pattern
+
+	set  *entrySet
+	tree pathSet // matches directories whose descendants all match
+}
+
+// NewRuleMatcher returns a new Matcher for an ordered list of rules.
+// opts may be nil.
+//
+// A rule is a glob pattern as in NewMatcher which excludes matching paths.
+// A rule starting with '!' is a negated rule which includes matching paths
+// again. Use '\!' for a pattern starting with a literal '!'.
+// The last rule which matches a path decides whether the path matches.
+// Paths which match no rule match.
+//
+// For example, the following rules exclude everything under build except
+// build/keep.txt:
+//
+//	build/**
+//	!build/keep.txt
+func NewRuleMatcher(rules []string, opts *MatcherOptions) (Matcher, error) {
+	if opts == nil {
+		opts = &MatcherOptions{}
+	}
+
+	m := &ruleMatcher{opts: opts}
+	for i, pattern := range rules {
+		r, err := newRule(pattern, i, opts)
+		if err != nil {
+			return nil, err
+		}
+		m.rules = append(m.rules, r)
+	}
+	return m, nil
+}
+
+// newRule returns a compiled rule. It returns a *PatternError if the rule
+// has a syntax error.
+func newRule(pattern string, index int, opts *MatcherOptions) (*rule, error) {
+	r := &rule{source: pattern, index: index, pattern: pattern}
+	if strings.HasPrefix(pattern, "!") {
+		r.negate = true
+		r.pattern = pattern[len("!"):]
+	} else if strings.HasPrefix(pattern, `\!`) {
+		r.pattern = pattern[len(`\`):]
+	}
+	if gerr := r.compile(opts); gerr != nil {
+		return nil, &PatternError{
+			Pattern: pattern,
+			Index:   index,
+			Offset:  len(pattern) - len(r.pattern) + gerr.offset,
+			Reason:  gerr.reason}
+	}
+	return r, nil
+}
+
+// compile parses r.pattern and compiles it. The offset of the returned
+// error is in r.pattern.
+func (r *rule) compile(opts *MatcherOptions) *globError {
+	var gerr *globError
+	r.globs, gerr = parsePattern(r.pattern, opts)
+	if gerr != nil {
+		return gerr
+	}
+	r.set = newEntrySet(r.globs, opts, newGlobSet)
+	r.tree = newGlobSet(treeGlobs(r.globs), opts)
+	return nil
+}
+
+func (m *ruleMatcher) Match(path string) bool {
+	path, isDir := m.opts.splitDirSlash(path)
+	r := m.lastMatch(path, isDir)
+	return r == nil || r.negate
+}
+
+// MatchEntry is like Match, but the entry is a directory if info tells so.
+func (m *ruleMatcher) MatchEntry(path string, info os.FileInfo) bool {
+	r := m.lastMatch(path, info.IsDir())
+	return r == nil || r.negate
+}
+
+func (m *ruleMatcher) lastMatch(path string, isDir bool) *rule {
+	for i := len(m.rules) - 1; i >= 0; i-- {
+		r := m.rules[i]
+		if r.set.match(path, isDir) {
+			return r
+		}
+	}
+	return nil
+}
+
+// Explain returns the explanation of Match(path) with the last rule which
+// matched the path.
+func (m *ruleMatcher) Explain(path string) *Explanation {
+	e := &Explanation{Path: path}
+	r := m.lastMatch(m.opts.splitDirSlash(path))
+	e.Matched = r == nil || r.negate
+	r.explain(e)
+	return e
+}
+
+// explain sets r to Include or Exclude of e. r may be nil.
+func (r *rule) explain(e *Explanation) {
+	if r == nil {
+		return
+	}
+	match := &PatternMatch{Index: r.index, Pattern: r.source, Line: r.line}
+	if r.negate {
+		e.Include = match
+	} else {
+		e.Exclude = match
+	}
+}
+
+// Prune returns true if a rule excludes dir and all paths under it, and no
+// negated rule after it may include a path under dir.
+func (m *ruleMatcher) Prune(dir string) bool {
+	for i := len(m.rules) - 1; i >= 0; i-- {
+		r := m.rules[i]
+		if r.negate {
+			if r.mayMatchUnder(dir, m.opts) {
+				return false
+			}
+		} else if r.tree != nil && r.tree.MatchString(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rule) mayMatchUnder(dir string, opts *MatcherOptions) bool {
+	for _, g := range r.globs {
+		if g.mayMatchUnder(dir, opts) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package paths_test
+
+import (
+	. "github.com/hnakamur/paths"
+	"testing"
+)
+
+func testRuleMatcher(t *testing.T, rules []string, cases []testCase) {
+	matcher, err := NewRuleMatcher(rules, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range cases {
+		actual := matcher.Match(c.path)
+		if actual != c.matches {
+			t.Errorf("path:%s\texpected:%v\tactual:%v", c.path, c.matches, actual)
+		}
+	}
+}
+
+func TestRuleMatcher(t *testing.T) {
+	testRuleMatcher(t,
+		[]string{
+			"build/**",
+			"!build/keep.txt",
+			"**/*.log",
+			"!important.log",
+			"tmp/",
+			"!tmp/*.txt",
+			"tmp/secret.txt",
+			`\!bang`,
+		},
+		[]testCase{
+			{"main.go", true},
+			{"build", false},
+			{"build/a.o", false},
+			{"build/keep.txt", true},
+			{"build/sub/keep.txt", false},
+			{"foo.log", false},
+			{"foo/bar.log", false},
+			{"important.log", true},
+			{"foo/important.log", false},
+			{"tmp/a.o", false},
+			{"tmp/a.txt", true},
+			{"tmp/secret.txt", false},
+			{"!bang", false},
+			{"bang", true},
+		})
+}
+
+func TestRuleMatcherPrune(t *testing.T) {
+	matcher, err := NewRuleMatcher([]string{
+		"vendor/",
+		"!**/*.go",
+		"**/.git/",
+		"build/**",
+		"!build/keep.txt",
+		"cache/",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pruner := matcher.(Pruner)
+	for _, c := range []testCase{
+		{".git", true},
+		{"src/.git", true},
+		{"build", false},
+		{"build/sub", true},
+		{"vendor", false},
+		{"cache", true},
+		{"src", false},
+	} {
+		actual := pruner.Prune(c.path)
+		if actual != c.matches {
+			t.Errorf("path:%s\texpected:%v\tactual:%v", c.path, c.matches, actual)
+		}
+	}
+}
+
+func TestRuleMatcherExplain(t *testing.T) {
+	matcher, err := NewRuleMatcher([]string{"build/**", "!build/keep.txt", "**/*.txt"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	explainer := matcher.(Explainer)
+
+	checkExplanation(t, explainer.Explain("main.go"), true, nil, nil)
+	checkExplanation(t, explainer.Explain("build/a.o"), false,
+		nil, &PatternMatch{Index: 0, Pattern: "build/**"})
+	checkExplanation(t, explainer.Explain("build/keep.txt"), false,
+		nil, &PatternMatch{Index: 2, Pattern: "**/*.txt"})
+
+	matcher, err = NewRuleMatcher([]string{"build/**", "!build/keep.txt"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkExplanation(t, matcher.(Explainer).Explain("build/keep.txt"), true,
+		&PatternMatch{Index: 1, Pattern: "!build/keep.txt"}, nil)
+}
@@ -0,0 +1,97 @@
+package paths
+
+import "time"
+
+// Stat holds details of a file which are not in os.FileInfo, like the ones
+// in *syscall.Stat_t on Linux. A fake file system can supply them to
+// entries by returning a *Stat from Sys() of its os.FileInfo.
+type Stat struct {
+	Ino    uint64    // inode number
+	Dev    uint64    // device number
+	Nlink  uint64    // number of hard links
+	Uid    uint32    // user ID of the owner
+	Gid    uint32    // group ID of the owner
+	Blocks int64     // number of 512-byte blocks allocated
+	Atime  time.Time // last access time
+	Ctime  time.Time // last status change time
+}
+
+// newStat returns the details in sys, or nil if they are not available.
+func newStat(sys interface{}) *Stat {
+	if st, ok := sys.(*Stat); ok {
+		return st
+	}
+	return sysStat(sys)
+}
+
+// Stat returns the details of the entry from Sys(), or nil if they are not
+// available. They are available if Sys() returns a *Stat, or a
+// *syscall.Stat_t on Linux.
+func (e *Entry) Stat() *Stat { return e.stat }
+
+// Ino returns the inode number, or zero if it is not available.
+func (e *Entry) Ino() uint64 {
+	if st := e.Stat(); st != nil {
+		return st.Ino
+	}
+	return 0
+}
+
+// Dev returns the device number, or zero if it is not available.
+func (e *Entry) Dev() uint64 {
+	if st := e.Stat(); st != nil {
+		return st.Dev
+	}
+	return 0
+}
+
+// Nlink returns the number of hard links, or zero if it is not available.
+func (e *Entry) Nlink() uint64 {
+	if st := e.Stat(); st != nil {
+		return st.Nlink
+	}
+	return 0
+}
+
+// Uid returns the user ID of the owner, or zero if it is not available.
+func (e *Entry) Uid() uint32 {
+	if st := e.Stat(); st != nil {
+		return st.Uid
+	}
+	return 0
+}
+
+// Gid returns the group ID of the owner, or zero if it is not available.
+func (e *Entry) Gid() uint32 {
+	if st := e.Stat(); st != nil {
+		return st.Gid
+	}
+	return 0
+}
+
+// Blocks returns the number of 512-byte blocks allocated, or zero if it is
+// not available.
+func (e *Entry) Blocks() int64 {
+	if st := e.Stat(); st != nil {
+		return st.Blocks
+	}
+	return 0
+}
+
+// Atime returns the last access time, or the zero time if it is not
+// available.
+func (e *Entry) Atime() time.Time {
+	if st := e.Stat(); st != nil {
+		return st.Atime
+	}
+	return time.Time{}
+}
+
+// Ctime returns the last status change time, or the zero time if it is not
+// available.
+func (e *Entry) Ctime() time.Time {
+	if st := e.Stat(); st != nil {
+		return st.Ctime
+	}
+	return time.Time{}
+}
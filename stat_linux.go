@@ -0,0 +1,23 @@
+package paths
+
+import (
+	"syscall"
+	"time"
+)
+
+// sysStat returns the details in sys if it is a *syscall.Stat_t.
+func sysStat(sys interface{}) *Stat {
+	st, ok := sys.(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	return &Stat{
+		Ino:    uint64(st.Ino),
+		Dev:    uint64(st.Dev),
+		Nlink:  uint64(st.Nlink),
+		Uid:    st.Uid,
+		Gid:    st.Gid,
+		Blocks: int64(st.Blocks),
+		Atime:  time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec)),
+		Ctime:  time.Unix(int64(st.Ctim.Sec), int64(st.Ctim.Nsec))}
+}
@@ -0,0 +1,48 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestEntryStatLinux(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "a")
+	if err := os.WriteFile(name, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(name, filepath.Join(dir, "b")); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := fi.Sys().(*syscall.Stat_t)
+
+	n := 0
+	for e, err := range Entries(filepath.ToSlash(dir), nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+		if e.Ino() != st.Ino || e.Dev() != uint64(st.Dev) || e.Nlink() != 2 ||
+			e.Uid() != st.Uid || e.Gid() != st.Gid {
+			t.Errorf("%s: Stat()=%+v, expected=%+v", e.Name(), e.Stat(), st)
+		}
+		if e.Stat() != e.Stat() {
+			t.Errorf("%s: Stat() returned a different *Stat on each call", e.Name())
+		}
+		if allocs := testing.AllocsPerRun(10, func() { e.Ino() }); allocs != 0 {
+			t.Errorf("%s: Ino() allocated %v times", e.Name(), allocs)
+		}
+		if e.Ctime().IsZero() || e.Atime().IsZero() {
+			t.Errorf("%s: Atime()=%v, Ctime()=%v", e.Name(), e.Atime(), e.Ctime())
+		}
+	}
+	if n != 2 {
+		t.Errorf("n=%d, expected=2", n)
+	}
+}
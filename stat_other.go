@@ -0,0 +1,9 @@
+//go:build !linux
+
+package paths
+
+// sysStat returns nil since *syscall.Stat_t is not supported on this
+// platform.
+func sysStat(sys interface{}) *Stat {
+	return nil
+}
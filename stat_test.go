@@ -0,0 +1,46 @@
+package paths_test
+
+import (
+	. "github.com/hnakamur/paths"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestEntryStat(t *testing.T) {
+	atime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	st := &Stat{
+		Ino: 42, Dev: 7, Nlink: 2, Uid: 1000, Gid: 100, Blocks: 8,
+		Atime: atime, Ctime: atime.Add(time.Hour)}
+	fsys := fstest.MapFS{
+		"dir/a": {Sys: st},
+		"dir/b": {},
+	}
+
+	var entries []*Entry
+	for e, err := range Entries("dir", &ReadDirOptions{FS: fsys}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("len(entries)=%d, expected=2", len(entries))
+	}
+
+	a := entries[0]
+	if a.Stat() != st {
+		t.Errorf("Stat()=%v, expected=%v", a.Stat(), st)
+	}
+	if a.Ino() != 42 || a.Dev() != 7 || a.Nlink() != 2 || a.Uid() != 1000 ||
+		a.Gid() != 100 || a.Blocks() != 8 ||
+		!a.Atime().Equal(st.Atime) || !a.Ctime().Equal(st.Ctime) {
+		t.Errorf("accessors=%d %d %d %d %d %d %v %v", a.Ino(), a.Dev(), a.Nlink(),
+			a.Uid(), a.Gid(), a.Blocks(), a.Atime(), a.Ctime())
+	}
+
+	b := entries[1]
+	if b.Stat() != nil || b.Ino() != 0 || !b.Atime().IsZero() {
+		t.Errorf("Stat()=%v, Ino()=%d, Atime()=%v, expected=nil, 0, zero", b.Stat(), b.Ino(), b.Atime())
+	}
+}
@@ -0,0 +1,201 @@
+package paths
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SymlinkPolicy specifies how symbolic links are handled when reading
+// directories recursively.
+//
+// When a symbolic link is followed, the returned entry describes the
+// target of the link, and the entry has a LinkTarget method which returns
+// the resolved path of the target:
+//
+//	if e, ok := fi.(interface{ LinkTarget() string }); ok {
+//		target := e.LinkTarget()
+//		...
+//	}
+//
+// LinkTarget returns an empty string for entries which are not followed
+// symbolic links. Links whose targets do not exist are not followed.
+type SymlinkPolicy int
+
+const (
+	// SymlinkNever does not follow symbolic links. This is the default.
+	SymlinkNever SymlinkPolicy = iota
+
+	// SymlinkFollow follows symbolic links. A link to a directory which
+	// is being walked, detected by the device and inode numbers or by the
+	// resolved path, is returned but not walked to avoid loops.
+	SymlinkFollow
+
+	// SymlinkFollowInRoot is like SymlinkFollow but follows only links
+	// whose targets are the root directory or under it.
+	SymlinkFollowInRoot
+)
+
+const maxSymlinks = 255
+
+var errTooManySymlinks = errors.New("paths: too many symbolic links")
+
+// osEvalSymlinks is filepath.EvalSymlinks for slash-separated paths.
+func osEvalSymlinks(name string) (string, error) {
+	target, err := filepath.EvalSymlinks(filepath.FromSlash(name))
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(target), nil
+}
+
+// osAbs is filepath.Abs for slash-separated paths.
+func osAbs(name string) (string, error) {
+	abs, err := filepath.Abs(filepath.FromSlash(name))
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(abs), nil
+}
+
+// readLinkFS is a file system which supports symbolic links. It is the
+// same as fs.ReadLinkFS of Go 1.25, declared here for older versions.
+type readLinkFS interface {
+	fs.FS
+	ReadLink(name string) (string, error)
+	Lstat(name string) (fs.FileInfo, error)
+}
+
+// fsEvalSymlinksFunc returns a function like filepath.EvalSymlinks for
+// fsys, or nil if fsys does not support symbolic links.
+func fsEvalSymlinksFunc(fsys fs.FS) func(string) (string, error) {
+	lfs, ok := fsys.(readLinkFS)
+	if !ok {
+		return nil
+	}
+	return func(name string) (string, error) {
+		return fsEvalSymlinks(lfs, name)
+	}
+}
+
+func fsEvalSymlinks(fsys readLinkFS, name string) (string, error) {
+	resolved := "."
+	rest := name
+	links := 0
+	for rest != "" && rest != "." {
+		var elem string
+		elem, rest, _ = strings.Cut(rest, "/")
+		p := path.Join(resolved, elem)
+		info, err := fsys.Lstat(p)
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			resolved = p
+			continue
+		}
+
+		links++
+		if links > maxSymlinks {
+			return "", &fs.PathError{Op: "evalsymlinks", Path: name, Err: errTooManySymlinks}
+		}
+		target, err := fsys.ReadLink(p)
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			return "", &fs.PathError{Op: "evalsymlinks", Path: name, Err: fs.ErrNotExist}
+		}
+		rest = path.Join(resolved, target, rest)
+		if rest == ".." || strings.HasPrefix(rest, "../") {
+			return "", &fs.PathError{Op: "evalsymlinks", Path: name, Err: fs.ErrNotExist}
+		}
+		resolved = "."
+	}
+	return resolved, nil
+}
+
+func (r *recurDirReader) followsSymlinks() bool {
+	return r.symlinks != SymlinkNever &&
+		r.evalSymlinksFunc != nil && r.statFunc != nil
+}
+
+// resolveDir returns the resolved path and the info of dirname.
+func (w *Walker) resolveDir(dirname string) (string, os.FileInfo) {
+	real, err := w.r.evalSymlinksFunc(dirname)
+	if err != nil {
+		real = dirname
+	}
+	info, err := w.r.statFunc(dirname)
+	if err != nil {
+		return real, nil
+	}
+	return real, info
+}
+
+// visit returns the info and the link target for the entry at name in d,
+// and the directory to walk if the entry should be walked.
+func (w *Walker) visit(d *walkDir, name string, info os.FileInfo) (os.FileInfo, string, *walkDir) {
+	if !w.r.followsSymlinks() {
+		if !info.IsDir() {
+			return info, "", nil
+		}
+		return info, "", &walkDir{dirname: name}
+	}
+
+	if info.Mode()&os.ModeSymlink == 0 {
+		if !info.IsDir() {
+			return info, "", nil
+		}
+		return info, "", &walkDir{
+			dirname: name,
+			real:    path.Join(d.real, info.Name()),
+			info:    info}
+	}
+
+	target, err := w.r.evalSymlinksFunc(name)
+	if err != nil {
+		return info, "", nil
+	}
+	if w.r.symlinks == SymlinkFollowInRoot && !w.inRoot(target) {
+		return info, "", nil
+	}
+	targetInfo, err := w.r.statFunc(target)
+	if err != nil {
+		return info, "", nil
+	}
+	if !targetInfo.IsDir() || w.isLoop(target, targetInfo) {
+		return targetInfo, target, nil
+	}
+	return targetInfo, target, &walkDir{
+		dirname: name,
+		real:    target,
+		info:    targetInfo}
+}
+
+// inRoot returns true if target is the root or under it. On the operating
+// system, target is made absolute like rootReal, since the target of an
+// absolute link is absolute even if the root is relative.
+func (w *Walker) inRoot(target string) bool {
+	if w.r.absFunc != nil {
+		abs, err := w.r.absFunc(target)
+		if err != nil {
+			return false
+		}
+		target = abs
+	}
+	return target == w.rootReal || isUnder(w.rootReal, target)
+}
+
+// isLoop returns true if the directory at real is being walked.
+func (w *Walker) isLoop(real string, info os.FileInfo) bool {
+	for _, d := range w.stack {
+		if d.real == real || (d.info != nil && os.SameFile(d.info, info)) {
+			return true
+		}
+	}
+	return false
+}
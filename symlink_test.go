@@ -0,0 +1,228 @@
+package paths
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type symlinkResult struct {
+	name   string
+	dir    bool
+	target string
+}
+
+func readSymlinkResults(t *testing.T, w *Walker, root string) []symlinkResult {
+	var results []symlinkResult
+	for w.Next() {
+		fi := w.Entry()
+		target := fi.(interface{ LinkTarget() string }).LinkTarget()
+		results = append(results, symlinkResult{
+			strings.TrimPrefix(fi.Name(), root+"/"),
+			fi.IsDir(),
+			strings.TrimPrefix(target, root+"/")})
+	}
+	if err := w.Err(); err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	return results
+}
+
+func checkSymlinkResults(t *testing.T, policy SymlinkPolicy, results, expected []symlinkResult) {
+	if len(results) != len(expected) {
+		t.Errorf("policy=%d: results=%v, expected=%v", policy, results, expected)
+		return
+	}
+	for i := range results {
+		if results[i] != expected[i] {
+			t.Errorf("policy=%d: results[%d]=%v, expected=%v", policy, i, results[i], expected[i])
+		}
+	}
+}
+
+func TestSymlinkPolicy(t *testing.T) {
+	tmp := t.TempDir()
+	root := filepath.ToSlash(filepath.Join(tmp, "root"))
+	outside := filepath.ToSlash(filepath.Join(tmp, "outside"))
+	for _, dir := range []string{root + "/a", root + "/loop", outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{root + "/a/f.txt", outside + "/g.txt"} {
+		if err := os.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{
+		root + "/b":         "a",
+		root + "/c.txt":     "a/f.txt",
+		root + "/dangling":  "nosuch",
+		root + "/loop/self": "..",
+		root + "/out":       outside,
+	} {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("cannot create symlink: %s", err)
+		}
+	}
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root = filepath.ToSlash(root)
+
+	for _, c := range []struct {
+		policy   SymlinkPolicy
+		expected []symlinkResult
+	}{
+		{SymlinkNever, []symlinkResult{
+			{"a", true, ""},
+			{"a/f.txt", false, ""},
+			{"b", false, ""},
+			{"c.txt", false, ""},
+			{"dangling", false, ""},
+			{"loop", true, ""},
+			{"loop/self", false, ""},
+			{"out", false, ""},
+		}},
+		{SymlinkFollow, []symlinkResult{
+			{"a", true, ""},
+			{"a/f.txt", false, ""},
+			{"b", true, "a"},
+			{"b/f.txt", false, ""},
+			{"c.txt", false, "a/f.txt"},
+			{"dangling", false, ""},
+			{"loop", true, ""},
+			{"loop/self", true, root},
+			{"out", true, outside},
+			{"out/g.txt", false, ""},
+		}},
+		{SymlinkFollowInRoot, []symlinkResult{
+			{"a", true, ""},
+			{"a/f.txt", false, ""},
+			{"b", true, "a"},
+			{"b/f.txt", false, ""},
+			{"c.txt", false, "a/f.txt"},
+			{"dangling", false, ""},
+			{"loop", true, ""},
+			{"loop/self", true, root},
+			{"out", false, ""},
+		}},
+	} {
+		w := NewWalker(root, &ReadDirOptions{Symlinks: c.policy})
+		checkSymlinkResults(t, c.policy, readSymlinkResults(t, w, root), c.expected)
+	}
+}
+
+func TestSymlinkPolicyFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"root/a/f.txt":  &fstest.MapFile{},
+		"root/b":        &fstest.MapFile{Mode: fs.ModeSymlink, Data: []byte("a")},
+		"root/loop/up":  &fstest.MapFile{Mode: fs.ModeSymlink, Data: []byte("../loop")},
+		"root/out":      &fstest.MapFile{Mode: fs.ModeSymlink, Data: []byte("../outside")},
+		"outside/g.txt": &fstest.MapFile{},
+	}
+	if _, ok := fs.FS(fsys).(readLinkFS); !ok {
+		t.Skip("fstest.MapFS does not support symbolic links before Go 1.25")
+	}
+
+	for _, c := range []struct {
+		policy   SymlinkPolicy
+		expected []symlinkResult
+	}{
+		{SymlinkFollow, []symlinkResult{
+			{"a", true, ""},
+			{"a/f.txt", false, ""},
+			{"b", true, "a"},
+			{"b/f.txt", false, ""},
+			{"loop", true, ""},
+			{"loop/up", true, "loop"},
+			{"out", true, "outside"},
+			{"out/g.txt", false, ""},
+		}},
+		{SymlinkFollowInRoot, []symlinkResult{
+			{"a", true, ""},
+			{"a/f.txt", false, ""},
+			{"b", true, "a"},
+			{"b/f.txt", false, ""},
+			{"loop", true, ""},
+			{"loop/up", true, "loop"},
+			{"out", false, ""},
+		}},
+	} {
+		w := NewWalker("root", &ReadDirOptions{FS: fsys, Symlinks: c.policy})
+		checkSymlinkResults(t, c.policy, readSymlinkResults(t, w, "root"), c.expected)
+	}
+}
+
+func TestSymlinkFollowPaging(t *testing.T) {
+	root := filepath.ToSlash(t.TempDir())
+	if err := os.MkdirAll(root+"/a/0", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("..", root+"/a/link"); err != nil {
+		t.Skipf("cannot create symlink: %s", err)
+	}
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root = filepath.ToSlash(root)
+
+	expected := []symlinkResult{
+		{"a", true, ""},
+		{"a/0", true, ""},
+		{"a/link", true, root},
+	}
+	results := readSymlinkResults(t, NewWalker(root, &ReadDirOptions{Symlinks: SymlinkFollow}), root)
+	checkSymlinkResults(t, SymlinkFollow, results, expected)
+
+	var paged []symlinkResult
+	marker := ""
+	for pages := 0; pages < len(expected)+1; pages++ {
+		w := NewWalker(root, &ReadDirOptions{Symlinks: SymlinkFollow, Marker: marker, MaxEntries: 1})
+		paged = append(paged, readSymlinkResults(t, w, root)...)
+		if !w.IsTruncated() {
+			break
+		}
+		marker = w.NextMarker()
+	}
+	checkSymlinkResults(t, SymlinkFollow, paged, expected)
+}
+
+func TestSymlinkFollowInRootRelative(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root = filepath.ToSlash(root)
+	if err := os.MkdirAll(root+"/releases/v1", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(root+"/releases/v1/app", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(root+"/releases/v1", root+"/current"); err != nil {
+		t.Skipf("cannot create symlink: %s", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	w := NewWalker(".", &ReadDirOptions{Symlinks: SymlinkFollowInRoot})
+	checkSymlinkResults(t, SymlinkFollowInRoot, readSymlinkResults(t, w, root), []symlinkResult{
+		{"current", true, "releases/v1"},
+		{"current/app", false, ""},
+		{"releases", true, ""},
+		{"releases/v1", true, ""},
+		{"releases/v1/app", false, ""},
+	})
+}
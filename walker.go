@@ -0,0 +1,603 @@
+package paths
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"iter"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ReadDirOptions holds options for reading directory entries recursively.
+// The zero value reads all entries.
+type ReadDirOptions struct {
+	// If Matcher is not nil, only entries which match will be returned.
+	// If Matcher is also a Pruner, directories pruned by it will not be
+	// read. If Matcher is an EntryMatcher, entries are matched with
+	// MatchEntry.
+	Matcher Matcher
+
+	// If Marker is not empty, entries after Marker will be returned.
+	Marker string
+
+	// If MaxEntries is greater than zero, the number of entries will be
+	// limited.
+	MaxEntries int
+
+	// If FS is not nil, directories are read from FS instead of the
+	// operating system. Paths are slash-separated paths in FS.
+	FS fs.FS
+
+	// If ContinueOnError is true, directories which cannot be read are
+	// skipped and the walk continues. The errors are returned together
+	// as *WalkErrors after the walk ends.
+	ContinueOnError bool
+
+	// Symlinks specifies how symbolic links are handled.
+	// When FS is set, symbolic links are followed only if FS has ReadLink
+	// and Lstat methods like fs.ReadLinkFS.
+	Symlinks SymlinkPolicy
+
+	// If IgnoreFile is not empty, the file with the name in each directory,
+	// like ".gitignore", is read in the format of ParseGitignore.
+	// Its rules apply to entries under the directory, and rules in a
+	// deeper directory take precedence. Ignored entries are not returned,
+	// and ignored directories are not read.
+	IgnoreFile string
+
+	// If CaseInsensitive is true, entries in each directory are sorted by
+	// names under the Unicode case folding, and Marker is compared in the
+	// same order. Names which differ only in case are sorted by bytes.
+	// Use MatcherOptions.CaseInsensitive for Matcher.
+	CaseInsensitive bool
+
+	// If MatchRelative is true, Matcher is given paths relative to the
+	// directory to read, so the same patterns work for "src", "./src" and
+	// "/path/to/src". Marker is still a path starting with the directory.
+	// Regardless of MatchRelative, returned entries have a RelPath method
+	// which returns the relative path:
+	//
+	//	if e, ok := fi.(interface{ RelPath() string }); ok {
+	//		rel := e.RelPath()
+	//		...
+	//	}
+	MatchRelative bool
+}
+
+// A WalkError records an error which occurred reading a directory.
+type WalkError struct {
+	Path string
+	Err  error
+}
+
+func (e *WalkError) Error() string { return e.Path + ": " + e.Err.Error() }
+func (e *WalkError) Unwrap() error { return e.Err }
+
+// WalkErrors is returned by Walker.Err when ContinueOnError is true and
+// some directories could not be read. Errors are in the walk order.
+type WalkErrors struct {
+	Errors []*WalkError
+}
+
+func (e *WalkErrors) Error() string {
+	if len(e.Errors) == 0 {
+		return "paths: no walk errors"
+	}
+	msg := e.Errors[0].Error()
+	if n := len(e.Errors) - 1; n == 1 {
+		msg += " (and 1 more error)"
+	} else if n > 1 {
+		msg += fmt.Sprintf(" (and %d more errors)", n)
+	}
+	return msg
+}
+
+func (e *WalkErrors) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Walker reads directory entries recursively one at a time, in the same
+// order as RecurReadDir. Directories are read only when the walk reaches
+// them, so memory usage does not grow with the number of entries.
+//
+//	w := paths.NewWalker(dir, nil)
+//	for w.Next() {
+//		fi := w.Entry()
+//		...
+//	}
+//	if err := w.Err(); err != nil {
+//		...
+//	}
+type Walker struct {
+	r     *recurDirReader
+	ctx   context.Context
+	root  string // cleaned path of the directory to read
+	stack []*walkDir
+	entry *Entry
+	count int
+	err   error
+	errs  []*WalkError // errors skipped by ContinueOnError
+
+	rootReal string // resolved absolute path of the root when following symlinks
+
+	last      string // path of the last entry returned by Next
+	peeked    bool   // whether the entry after the limit was looked for
+	truncated bool   // whether an entry after the limit was found
+}
+
+// walkDir is a directory on the walk stack.
+type walkDir struct {
+	dirname string
+	infos   []os.FileInfo // entries which are not visited yet
+	loaded  bool
+
+	// after and descend are used to resume a walk from a marker.
+	// When the directory is loaded, entries up to after are skipped, and
+	// if descend is true and after is a directory, it is walked first.
+	after   string
+	descend bool
+
+	// real and info are the resolved path and the info of the directory.
+	// They are set only when symbolic links are followed.
+	real string
+	info os.FileInfo
+
+	// ignore is the rules in the ignore file of the directory.
+	ignore       *gitignoreMatcher
+	ignoreLoaded bool
+}
+
+// NewWalker returns a new Walker which reads entries under dir.
+// opts may be nil.
+func NewWalker(dir string, opts *ReadDirOptions) *Walker {
+	return NewWalkerContext(context.Background(), dir, opts)
+}
+
+// NewWalkerContext is like NewWalker but the walk stops when ctx is done.
+// ctx is checked before reading each directory and each entry, and
+// Err returns ctx.Err() when the walk is stopped by ctx.
+func NewWalkerContext(ctx context.Context, dir string, opts *ReadDirOptions) *Walker {
+	r := newRecurDirReader(dir, opts)
+	r.ctx = ctx
+	return r.newWalker()
+}
+
+// All returns an iterator over entries under dir, in the same order as
+// RecurReadDir. opts may be nil.
+// If an error occurs, it is yielded with a nil entry as the last value.
+// Stopping the iteration stops the walk without reading any more
+// directories.
+//
+//	for fi, err := range paths.All(dir, nil) {
+//		if err != nil {
+//			...
+//		}
+//		...
+//	}
+func All(dir string, opts *ReadDirOptions) iter.Seq2[os.FileInfo, error] {
+	return newRecurDirReader(dir, opts).all()
+}
+
+// Entries is like All, but Name() for an entry returns the base name.
+//
+//	for e, err := range paths.Entries(dir, nil) {
+//		if err != nil {
+//			...
+//		}
+//		fmt.Println(e.Depth(), e.Path())
+//	}
+func Entries(dir string, opts *ReadDirOptions) iter.Seq2[*Entry, error] {
+	return newRecurDirReader(dir, opts).entries()
+}
+
+// A Page is a result of RecurReadDirPage.
+type Page struct {
+	// Entries read in the page. Name() for an entry returns a path
+	// starting with dir.
+	Entries []os.FileInfo
+
+	// IsTruncated is true if more entries remain after Entries.
+	IsTruncated bool
+
+	// NextMarker is the marker to read the next page if IsTruncated is
+	// true. Otherwise it is empty.
+	NextMarker string
+}
+
+// RecurReadDirPage reads a page of entries under dir, in the same order
+// as RecurReadDir. opts may be nil. opts.MaxEntries is the page size.
+// The next page can be read by setting opts.Marker to NextMarker of the
+// result, even if entries are filtered by opts.Matcher.
+//
+//	opts := &paths.ReadDirOptions{MaxEntries: 1000}
+//	for {
+//		page, err := paths.RecurReadDirPage(ctx, dir, opts)
+//		if err != nil {
+//			...
+//		}
+//		...
+//		if !page.IsTruncated {
+//			break
+//		}
+//		opts.Marker = page.NextMarker
+//	}
+//
+// If an error occurs, the page read so far is returned with the error.
+func RecurReadDirPage(ctx context.Context, dir string, opts *ReadDirOptions) (*Page, error) {
+	return NewWalkerContext(ctx, dir, opts).readPage()
+}
+
+func (w *Walker) readPage() (*Page, error) {
+	page := &Page{Entries: make([]os.FileInfo, 0)}
+	for w.Next() {
+		page.Entries = append(page.Entries, w.Entry())
+	}
+	page.IsTruncated = w.IsTruncated()
+	page.NextMarker = w.NextMarker()
+	return page, w.Err()
+}
+
+func newRecurDirReader(dir string, opts *ReadDirOptions) *recurDirReader {
+	r := &recurDirReader{
+		dir:              dir,
+		readDirFunc:      ioutil.ReadDir,
+		readFileFunc:     os.ReadFile,
+		statFunc:         os.Stat,
+		evalSymlinksFunc: osEvalSymlinks,
+		absFunc:          osAbs}
+	if opts != nil {
+		r.matcher = opts.Matcher
+		r.marker = opts.Marker
+		r.maxEntries = opts.MaxEntries
+		r.continueOnError = opts.ContinueOnError
+		r.symlinks = opts.Symlinks
+		r.ignoreFile = opts.IgnoreFile
+		r.foldCase = opts.CaseInsensitive
+		r.matchRelative = opts.MatchRelative
+		if opts.FS != nil {
+			fsys := opts.FS
+			r.readDirFunc = fsReadDirFunc(fsys)
+			r.readFileFunc = func(name string) ([]byte, error) {
+				return fs.ReadFile(fsys, name)
+			}
+			r.statFunc = func(name string) (os.FileInfo, error) {
+				return fs.Stat(fsys, name)
+			}
+			r.evalSymlinksFunc = fsEvalSymlinksFunc(fsys)
+			r.absFunc = nil
+		}
+	}
+	return r
+}
+
+func (r *recurDirReader) all() iter.Seq2[os.FileInfo, error] {
+	return func(yield func(os.FileInfo, error) bool) {
+		for e, err := range r.entries() {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(dirEntry{e}, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (r *recurDirReader) entries() iter.Seq2[*Entry, error] {
+	return func(yield func(*Entry, error) bool) {
+		w := r.newWalker()
+		for w.Next() {
+			if !yield(w.Current(), nil) {
+				return
+			}
+		}
+		if err := w.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+func (r *recurDirReader) newWalker() *Walker {
+	w := &Walker{r: r, ctx: r.ctx}
+	if w.ctx == nil {
+		w.ctx = context.Background()
+	}
+	root := path.Clean(r.dir)
+	w.root = root
+	if r.followsSymlinks() {
+		w.rootReal, _ = w.resolveDir(root)
+		if r.absFunc != nil {
+			if abs, err := r.absFunc(w.rootReal); err == nil {
+				w.rootReal = abs
+			}
+		}
+	}
+	if r.marker == "" {
+		w.stack = []*walkDir{{dirname: root}}
+		return w
+	}
+	if err := validateMarker(root, r.marker); err != nil {
+		w.err = err
+		return w
+	}
+
+	marker := r.marker
+	descend := true
+	for {
+		markerDir := path.Dir(marker)
+		w.stack = append(w.stack, &walkDir{
+			dirname: markerDir,
+			after:   path.Base(marker),
+			descend: descend,
+		})
+		if markerDir == root {
+			break
+		}
+		marker = markerDir
+		descend = false
+	}
+	// The innermost directory must be on the top of the stack.
+	for i, j := 0, len(w.stack)-1; i < j; i, j = i+1, j-1 {
+		w.stack[i], w.stack[j] = w.stack[j], w.stack[i]
+	}
+	if r.followsSymlinks() {
+		// Resolve the directories before loading them, so loops back to
+		// them are detected from the marker directory.
+		for _, d := range w.stack {
+			d.real, d.info = w.resolveDir(d.dirname)
+		}
+	}
+	return w
+}
+
+// validateMarker checks that marker is a clean path under root.
+// root must be a clean path.
+func validateMarker(root, marker string) error {
+	if marker != path.Clean(marker) || marker == root {
+		return fmt.Errorf("%w: %q", ErrInvalidMarker, marker)
+	}
+
+	if !isUnder(root, marker) {
+		return fmt.Errorf("%w: %q is not under %q", ErrMarkerOutsideRoot, marker, root)
+	}
+	return nil
+}
+
+// isUnder returns true if name is under root. Both must be clean paths.
+func isUnder(root, name string) bool {
+	switch root {
+	case ".":
+		return name != "." && name != ".." &&
+			!strings.HasPrefix(name, "../") && !strings.HasPrefix(name, "/")
+	case "/":
+		return name != "/" && strings.HasPrefix(name, "/")
+	default:
+		return strings.HasPrefix(name, root+"/")
+	}
+}
+
+// relPath returns name relative to base. name must be under base.
+func relPath(base, name string) string {
+	switch base {
+	case ".":
+		return name
+	case "/":
+		return name[len("/"):]
+	default:
+		return name[len(base)+len("/"):]
+	}
+}
+
+// Next advances the Walker to the next entry, which will then be available
+// through the Entry method. It returns false when the walk stops, either by
+// reaching the end of entries, the limit of entries or an error.
+// After Next returns false, the Err method will return any error that
+// occurred during the walk.
+func (w *Walker) Next() bool {
+	w.entry = nil
+	if w.err != nil || w.hasReachedLimit() {
+		return false
+	}
+	if !w.next() {
+		return false
+	}
+	w.count++
+	w.last = w.entry.Path()
+	return true
+}
+
+// next advances to the next matching entry regardless of the limit.
+func (w *Walker) next() bool {
+	for len(w.stack) > 0 {
+		if err := w.ctx.Err(); err != nil {
+			w.err = err
+			return false
+		}
+
+		d := w.stack[len(w.stack)-1]
+		if !d.loaded {
+			if err := w.load(d); err != nil {
+				if !w.r.continueOnError {
+					w.err = err
+					return false
+				}
+				w.errs = append(w.errs, &WalkError{d.dirname, err})
+				d.loaded = true
+			}
+			continue
+		}
+		if len(d.infos) == 0 {
+			w.stack = w.stack[:len(w.stack)-1]
+			continue
+		}
+
+		info := d.infos[0]
+		d.infos = d.infos[1:]
+		entryPath := path.Join(d.dirname, info.Name())
+		info, target, sub := w.visit(d, entryPath, info)
+		if w.r.ignoreFile != "" {
+			ignored, err := w.ignored(entryPath, info.IsDir())
+			if err != nil {
+				if !w.r.continueOnError {
+					w.err = err
+					return false
+				}
+				w.errs = append(w.errs, err)
+			}
+			if ignored {
+				continue
+			}
+		}
+		rel := relPath(w.root, entryPath)
+		if sub != nil && !w.r.prune(w.matchPath(entryPath, rel)) {
+			w.stack = append(w.stack, sub)
+		}
+		if w.r.matcher == nil || matchEntry(w.r.matcher, w.matchPath(entryPath, rel), info) {
+			entry := newEntry(entryPath, info)
+			entry.rel = rel
+			entry.target = target
+			w.entry = entry
+			return true
+		}
+	}
+	return false
+}
+
+// load reads entries of d. If d was created from a marker, entries up to
+// the marker are skipped by the sort order, so the walk can be resumed even
+// if the marker or its parent directories were removed or replaced by
+// files. The marker directory is pushed to the stack if it still exists.
+func (w *Walker) load(d *walkDir) error {
+	infos, err := w.r.readDirFunc(d.dirname)
+	if err != nil {
+		if d.after != "" && d.dirname != w.root && w.isRemovedDir(d.dirname, err) {
+			d.loaded = true
+			return nil
+		}
+		return err
+	}
+	d.loaded = true
+	if w.r.foldCase {
+		sort.SliceStable(infos, func(i, j int) bool {
+			return compareNames(infos[i].Name(), infos[j].Name(), true) < 0
+		})
+	}
+	d.infos = infos
+	if w.r.followsSymlinks() && d.real == "" {
+		d.real, d.info = w.resolveDir(d.dirname)
+	}
+
+	if d.after != "" {
+		i := sort.Search(len(infos), func(i int) bool {
+			return compareNames(infos[i].Name(), d.after, w.r.foldCase) > 0
+		})
+		d.infos = infos[i:]
+		if d.descend && i > 0 && infos[i-1].Name() == d.after {
+			subdir := path.Join(d.dirname, d.after)
+			_, _, sub := w.visit(d, subdir, infos[i-1])
+			if sub != nil && !w.r.prune(w.matchPath(subdir, relPath(w.root, subdir))) {
+				w.stack = append(w.stack, sub)
+			}
+		}
+	}
+	return nil
+}
+
+// isRemovedDir returns true if reading dirname failed with err because it
+// is no longer a directory.
+func (w *Walker) isRemovedDir(dirname string, err error) bool {
+	if errors.Is(err, fs.ErrNotExist) {
+		return true
+	}
+	if w.r.statFunc == nil {
+		return false
+	}
+	info, serr := w.r.statFunc(dirname)
+	return serr == nil && !info.IsDir()
+}
+
+// matchPath returns the path given to the matcher for the entry at name
+// whose path relative to the root is rel.
+func (w *Walker) matchPath(name, rel string) string {
+	if w.r.matchRelative {
+		return rel
+	}
+	return name
+}
+
+// prune returns true if no entry under dir can match.
+func (r *recurDirReader) prune(dir string) bool {
+	p, ok := r.matcher.(Pruner)
+	return ok && p.Prune(dir)
+}
+
+func (w *Walker) hasReachedLimit() bool {
+	return w.r.maxEntries > 0 && w.count >= w.r.maxEntries
+}
+
+// Entry returns the current entry. Name() for the entry returns a path
+// starting with the directory passed to NewWalker, as in RecurReadDir.
+// The entry also has the methods of *Entry other than Name.
+// Use Current to get the entry whose Name() returns the base name.
+func (w *Walker) Entry() os.FileInfo {
+	if w.entry == nil {
+		return nil
+	}
+	return dirEntry{w.entry}
+}
+
+// Current returns the current entry.
+func (w *Walker) Current() *Entry {
+	return w.entry
+}
+
+// IsTruncated reports whether the walk stopped at the limit of entries
+// while more matching entries remain. It should be called after Next
+// returns false. To find out, it may read directories until the next
+// matching entry is found. If an error occurs while reading them, it
+// returns true and Err returns the error.
+func (w *Walker) IsTruncated() bool {
+	if !w.hasReachedLimit() {
+		return false
+	}
+	if !w.peeked && w.err == nil {
+		w.peeked = true
+		n := len(w.errs)
+		w.truncated = w.next()
+		w.entry = nil
+		if w.truncated {
+			// Errors after the limit will be reported in the next page.
+			w.errs = w.errs[:n]
+		}
+	}
+	return w.truncated || w.err != nil
+}
+
+// NextMarker returns the marker for reading entries after the ones
+// returned by Next, or an empty string if IsTruncated returns false.
+func (w *Walker) NextMarker() string {
+	if !w.IsTruncated() {
+		return ""
+	}
+	return w.last
+}
+
+// Err returns the first error that occurred during the walk.
+// If ContinueOnError is true, errors reading directories are returned as
+// *WalkErrors, unless the walk is stopped by another error.
+func (w *Walker) Err() error {
+	if w.err == nil && len(w.errs) > 0 {
+		return &WalkErrors{w.errs}
+	}
+	return w.err
+}
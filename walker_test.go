@@ -0,0 +1,411 @@
+package paths
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWalker(t *testing.T) {
+	all := []os.FileInfo{
+		&resultFileInfo{true, "archive/tar"},
+		&resultFileInfo{false, "archive/tar/common.go"},
+		&resultFileInfo{false, "archive/tar/example_test.go"},
+		&resultFileInfo{false, "archive/tar/reader.go"},
+		&resultFileInfo{false, "archive/tar/reader_test.go"},
+		&resultFileInfo{true, "archive/tar/testdata"},
+		&resultFileInfo{false, "archive/tar/testdata/gnu.tar"},
+		&resultFileInfo{false, "archive/tar/testdata/pax.tar"},
+		&resultFileInfo{false, "archive/tar/testdata/small.txt"},
+		&resultFileInfo{false, "archive/tar/writer.go"},
+		&resultFileInfo{false, "archive/tar/writer_test.go"},
+		&resultFileInfo{true, "archive/zip"},
+		&resultFileInfo{false, "archive/zip/example_test.go"},
+		&resultFileInfo{false, "archive/zip/reader.go"},
+		&resultFileInfo{false, "archive/zip/reader_test.go"},
+		&resultFileInfo{false, "archive/zip/struct.go"},
+		&resultFileInfo{true, "archive/zip/testdata"},
+		&resultFileInfo{false, "archive/zip/testdata/crc32-not-streamed.zip"},
+		&resultFileInfo{false, "archive/zip/testdata/dd.zip"},
+		&resultFileInfo{false, "archive/zip/testdata/go-no-datadesc-sig.zip"},
+		&resultFileInfo{false, "archive/zip/writer.go"},
+		&resultFileInfo{false, "archive/zip/writer_test.go"},
+		&resultFileInfo{false, "archive/zip/zip_test.go"},
+	}
+	for _, c := range []struct {
+		marker   string
+		expected []os.FileInfo
+	}{
+		{"", all},
+		{"archive/tar/reader.go", all[4:]},
+		{"archive/tar/testdata", all[6:]},
+		{"archive/tar/writer_test.go", all[11:]},
+	} {
+		r := recurDirReader{
+			dir: "archive", marker: c.marker,
+			readDirFunc: fakeReaderDirFunc(newFakeFS())}
+		w := r.newWalker()
+		var fis []os.FileInfo
+		for w.Next() {
+			fis = append(fis, w.Entry())
+		}
+		if err := w.Err(); err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		checkFileInfos(t, fis, c.expected)
+	}
+}
+
+func TestWalkerReadsDirLazily(t *testing.T) {
+	var readDirs []string
+	readDir := fakeReaderDirFunc(newFakeFS())
+	r := recurDirReader{
+		dir: "archive",
+		readDirFunc: func(dirname string) ([]os.FileInfo, error) {
+			readDirs = append(readDirs, dirname)
+			return readDir(dirname)
+		}}
+	w := r.newWalker()
+	for i := 0; i < 2; i++ {
+		if !w.Next() {
+			t.Fatalf("Unexpected end of walk: %v\n", w.Err())
+		}
+	}
+	checkFileInfo(t, w.Entry(), &resultFileInfo{false, "archive/tar/common.go"})
+	if len(readDirs) != 2 {
+		t.Errorf("readDirs=%v, expected=[archive archive/tar]", readDirs)
+	}
+}
+
+func TestWalkerError(t *testing.T) {
+	r := recurDirReader{
+		dir:         "nosuchdir",
+		readDirFunc: fakeReaderDirFunc(newFakeFS())}
+	w := r.newWalker()
+	if w.Next() {
+		t.Errorf("Next()=true, expected=false")
+	}
+	if w.Err() != os.ErrInvalid {
+		t.Errorf("Err()=%v, expected=%v", w.Err(), os.ErrInvalid)
+	}
+}
+
+func TestAll(t *testing.T) {
+	matcher, err := NewMatcher(nil, []string{"**/testdata/**"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	r := recurDirReader{
+		dir: "archive", marker: "archive/tar/reader.go",
+		maxEntries: 8, matcher: matcher,
+		readDirFunc: fakeReaderDirFunc(newFakeFS())}
+	expected, err := r.recurReadDir()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	var fis []os.FileInfo
+	for fi, err := range r.all() {
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		fis = append(fis, fi)
+	}
+	checkFileInfos(t, fis, expected)
+}
+
+func TestAllBreak(t *testing.T) {
+	var readDirs []string
+	readDir := fakeReaderDirFunc(newFakeFS())
+	r := recurDirReader{
+		dir: "archive",
+		readDirFunc: func(dirname string) ([]os.FileInfo, error) {
+			readDirs = append(readDirs, dirname)
+			return readDir(dirname)
+		}}
+	for fi, err := range r.all() {
+		if err != nil {
+			t.Fatalf("Unexpected error: %s\n", err)
+		}
+		if fi.Name() == "archive/tar/testdata" {
+			break
+		}
+	}
+	if len(readDirs) != 2 {
+		t.Errorf("readDirs=%v, expected=[archive archive/tar]", readDirs)
+	}
+}
+
+func TestAllError(t *testing.T) {
+	r := recurDirReader{
+		dir:         "nosuchdir",
+		readDirFunc: fakeReaderDirFunc(newFakeFS())}
+	n := 0
+	for fi, err := range r.all() {
+		n++
+		if fi != nil || err != os.ErrInvalid {
+			t.Errorf("fi=%v, err=%v, expected=nil, %v", fi, err, os.ErrInvalid)
+		}
+	}
+	if n != 1 {
+		t.Errorf("n=%d, expected=1", n)
+	}
+}
+
+func TestReadPage(t *testing.T) {
+	matcher, err := NewMatcher(nil, []string{"**/testdata/*", "**/*_test.go"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	r := recurDirReader{
+		dir: "archive", matcher: matcher,
+		readDirFunc: fakeReaderDirFunc(newFakeFS())}
+	expected, err := r.recurReadDir()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+
+	for _, maxEntries := range []int{1, 3, 4, len(expected) - 1, len(expected), len(expected) + 1} {
+		var fis []os.FileInfo
+		r.marker = ""
+		r.maxEntries = maxEntries
+		for pages := 1; ; pages++ {
+			page, err := r.newWalker().readPage()
+			if err != nil {
+				t.Fatalf("Unexpected error: %s\n", err)
+			}
+			fis = append(fis, page.Entries...)
+			if !page.IsTruncated {
+				if page.NextMarker != "" {
+					t.Errorf("NextMarker=%s, expected empty", page.NextMarker)
+				}
+				if wantPages := (len(expected) + maxEntries - 1) / maxEntries; pages != wantPages {
+					t.Errorf("maxEntries=%d, pages=%d, expected=%d", maxEntries, pages, wantPages)
+				}
+				break
+			}
+			r.marker = page.NextMarker
+		}
+		checkFileInfos(t, fis, expected)
+	}
+}
+
+func TestWalkerContinueOnError(t *testing.T) {
+	readDir := fakeReaderDirFunc(newFakeFS())
+	r := recurDirReader{
+		dir: "archive", continueOnError: true,
+		readDirFunc: func(dirname string) ([]os.FileInfo, error) {
+			if path.Base(dirname) == "testdata" {
+				return nil, os.ErrPermission
+			}
+			return readDir(dirname)
+		}}
+	fis, err := r.recurReadDir()
+
+	var walkErrs *WalkErrors
+	if !errors.As(err, &walkErrs) {
+		t.Fatalf("err=%v, expected *WalkErrors", err)
+	}
+	if len(walkErrs.Errors) != 2 ||
+		walkErrs.Errors[0].Path != "archive/tar/testdata" ||
+		walkErrs.Errors[1].Path != "archive/zip/testdata" {
+		t.Errorf("Errors=%v", walkErrs.Errors)
+	}
+	if !errors.Is(err, os.ErrPermission) {
+		t.Errorf("errors.Is(%v, os.ErrPermission)=false, expected=true", err)
+	}
+	if len(fis) != 17 {
+		t.Errorf("len(fis)=%d, expected=17", len(fis))
+	}
+}
+
+func TestWalkErrorsEmpty(t *testing.T) {
+	if msg := (&WalkErrors{}).Error(); msg == "" {
+		t.Errorf("Error()=%q, expected non-empty", msg)
+	}
+}
+
+func TestReadPageContinueOnError(t *testing.T) {
+	readDir := fakeReaderDirFunc(newFakeFS())
+	r := recurDirReader{
+		dir: "archive", maxEntries: 5, continueOnError: true,
+		readDirFunc: func(dirname string) ([]os.FileInfo, error) {
+			if dirname == "archive/tar/testdata" {
+				return nil, os.ErrPermission
+			}
+			return readDir(dirname)
+		}}
+	page, err := r.newWalker().readPage()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	if !page.IsTruncated || page.NextMarker != "archive/tar/reader_test.go" {
+		t.Errorf("IsTruncated=%v, NextMarker=%s", page.IsTruncated, page.NextMarker)
+	}
+
+	r.marker = page.NextMarker
+	page, err = r.newWalker().readPage()
+	var walkErrs *WalkErrors
+	if !errors.As(err, &walkErrs) || len(walkErrs.Errors) != 1 {
+		t.Fatalf("err=%v, expected *WalkErrors with 1 error", err)
+	}
+	checkFileInfos(t, page.Entries, []os.FileInfo{
+		&resultFileInfo{true, "archive/tar/testdata"},
+		&resultFileInfo{false, "archive/tar/writer.go"},
+		&resultFileInfo{false, "archive/tar/writer_test.go"},
+		&resultFileInfo{true, "archive/zip"},
+		&resultFileInfo{false, "archive/zip/example_test.go"},
+	})
+}
+
+func TestReadPageContinueOnErrorLastPage(t *testing.T) {
+	matcher, err := NewMatcher([]string{"**/testdata"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s\n", err)
+	}
+	readDir := fakeReaderDirFunc(newFakeFS())
+	r := recurDirReader{
+		dir: "archive", matcher: matcher, maxEntries: 2, continueOnError: true,
+		readDirFunc: func(dirname string) ([]os.FileInfo, error) {
+			if dirname == "archive/zip/testdata" {
+				return nil, os.ErrPermission
+			}
+			return readDir(dirname)
+		}}
+	page, err := r.newWalker().readPage()
+	var walkErrs *WalkErrors
+	if !errors.As(err, &walkErrs) || len(walkErrs.Errors) != 1 ||
+		walkErrs.Errors[0].Path != "archive/zip/testdata" {
+		t.Fatalf("err=%v, expected *WalkErrors for archive/zip/testdata", err)
+	}
+	if page.IsTruncated || page.NextMarker != "" {
+		t.Errorf("IsTruncated=%v, NextMarker=%s, expected=false, empty", page.IsTruncated, page.NextMarker)
+	}
+	checkFileInfos(t, page.Entries, []os.FileInfo{
+		&resultFileInfo{true, "archive/tar/testdata"},
+		&resultFileInfo{true, "archive/zip/testdata"},
+	})
+}
+
+func TestWalkerCaseInsensitive(t *testing.T) {
+	fsys := fstest.MapFS{
+		"dir/b":   {},
+		"dir/A/x": {},
+		"dir/a":   {},
+		"dir/C":   {},
+		"dir/é":   {},
+		"dir/É":   {},
+	}
+	for _, c := range []struct {
+		marker   string
+		fold     bool
+		expected []string
+	}{
+		{"", false, []string{"dir/A", "dir/A/x", "dir/C", "dir/a", "dir/b", "dir/É", "dir/é"}},
+		{"", true, []string{"dir/A", "dir/A/x", "dir/a", "dir/b", "dir/C", "dir/É", "dir/é"}},
+		{"dir/A", true, []string{"dir/A/x", "dir/a", "dir/b", "dir/C", "dir/É", "dir/é"}},
+		{"dir/B", true, []string{"dir/b", "dir/C", "dir/É", "dir/é"}},
+		{"dir/b", true, []string{"dir/C", "dir/É", "dir/é"}},
+		{"dir/É", true, []string{"dir/é"}},
+	} {
+		var names []string
+		for fi, err := range All("dir", &ReadDirOptions{FS: fsys, Marker: c.marker, CaseInsensitive: c.fold}) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, fi.Name())
+		}
+		if strings.Join(names, " ") != strings.Join(c.expected, " ") {
+			t.Errorf("marker=%s, fold=%v: names=%v, expected=%v", c.marker, c.fold, names, c.expected)
+		}
+	}
+}
+
+func TestWalkerMatchRelative(t *testing.T) {
+	fsys := fstest.MapFS{
+		"src/main.go":        {},
+		"src/src/lib.go":     {},
+		"src/vendor/x/a.go":  {},
+		"src/docs/README.md": {},
+	}
+	matcher, err := NewMatcher([]string{"src/**/*.go", "*.go"}, []string{"vendor/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"main.go", "src/lib.go"}
+	for _, dir := range []string{"src", "./src", "src/"} {
+		var rels []string
+		for fi, err := range All(dir, &ReadDirOptions{FS: fsys, Matcher: matcher, MatchRelative: true}) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := fi.(interface{ RelPath() string })
+			if fi.Name() != path.Join("src", e.RelPath()) {
+				t.Errorf("Name()=%s, RelPath()=%s", fi.Name(), e.RelPath())
+			}
+			rels = append(rels, e.RelPath())
+		}
+		if strings.Join(rels, " ") != strings.Join(expected, " ") {
+			t.Errorf("dir=%s: rels=%v, expected=%v", dir, rels, expected)
+		}
+	}
+
+	var names []string
+	for fi, err := range All("src", &ReadDirOptions{FS: fsys, Matcher: matcher}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, fi.Name())
+	}
+	if expected := []string{"src/main.go", "src/src/lib.go", "src/vendor/x/a.go"}; strings.Join(names, " ") != strings.Join(expected, " ") {
+		t.Errorf("names=%v, expected=%v", names, expected)
+	}
+}
+
+func TestEntries(t *testing.T) {
+	fsys := fstest.MapFS{
+		"root/a.txt":     {},
+		"root/lib/b.go":  {},
+		"root/lib/c/d.c": {},
+	}
+	type result struct {
+		name, path, rel, parent string
+		depth                   int
+	}
+	expected := []result{
+		{"a.txt", "root/a.txt", "a.txt", "root", 1},
+		{"lib", "root/lib", "lib", "root", 1},
+		{"b.go", "root/lib/b.go", "lib/b.go", "root/lib", 2},
+		{"c", "root/lib/c", "lib/c", "root/lib", 2},
+		{"d.c", "root/lib/c/d.c", "lib/c/d.c", "root/lib/c", 3},
+	}
+	var results []result
+	for e, err := range Entries("./root", &ReadDirOptions{FS: fsys}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, result{e.Name(), e.Path(), e.RelPath(), e.Parent(), e.Depth()})
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("results=%v, expected=%v", results, expected)
+	}
+	for i := range results {
+		if results[i] != expected[i] {
+			t.Errorf("result=%v, expected=%v", results[i], expected[i])
+		}
+	}
+
+	// All and RecurReadDir keep returning paths for Name.
+	for fi, err := range All("root", &ReadDirOptions{FS: fsys, MaxEntries: 1}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		e := fi.(interface{ Depth() int })
+		if fi.Name() != "root/a.txt" || e.Depth() != 1 {
+			t.Errorf("Name()=%s, Depth()=%d, expected=root/a.txt, 1", fi.Name(), e.Depth())
+		}
+	}
+}